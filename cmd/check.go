@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+)
+
+// runState collects what a run found while walking its paths, for the module checks
+// that follow the walk and the final check result.
+type runState struct {
+	// filter selects the files and directories to process.
+	filter *hclsort.PathFilter
+	// processed lists the files that passed the filters and were processed, by directory.
+	processed map[string][]string
+	// roots holds the walk root each directory of processed files was reached from, which
+	// the filter matches the other files of its module against.
+	roots map[string]string
+	// unsorted collects the files found unsorted in check mode.
+	unsorted map[string]bool
+	// sortChanges holds the sorting changes of a dry run that also rearranges modules.
+	// They are printed after the module changes, unless those cover the file already.
+	sortChanges map[string]hclsort.FileChange
+}
+
+// newRunState returns an empty runState that processes the paths filter selects.
+func newRunState(filter *hclsort.PathFilter) *runState {
+	return &runState{
+		filter:      filter,
+		processed:   map[string][]string{},
+		roots:       map[string]string{},
+		unsorted:    map[string]bool{},
+		sortChanges: map[string]hclsort.FileChange{},
+	}
+}
+
+// record notes that the file at path, reached while walking root, was processed.
+func (s *runState) record(root, path string) {
+	dir := filepath.Dir(path)
+	if _, ok := s.roots[dir]; !ok {
+		s.roots[dir] = root
+	}
+	if !slices.Contains(s.processed[dir], path) {
+		s.processed[dir] = append(s.processed[dir], path)
+	}
+}
+
+// forgetDeleted drops the files that changes delete from the processed files.
+func (s *runState) forgetDeleted(changes []hclsort.FileChange) {
+	for _, change := range changes {
+		if change.After != nil {
+			continue
+		}
+		dir := filepath.Dir(change.Path)
+		s.processed[dir] = slices.DeleteFunc(s.processed[dir], func(path string) bool {
+			return filepath.Clean(path) == filepath.Clean(change.Path)
+		})
+	}
+}
+
+// dirs returns the directories of the processed files in name order.
+func (s *runState) dirs() []string {
+	dirs := make([]string, 0, len(s.processed))
+	for dir := range s.processed {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// moduleFiles returns the files of the module in dir that the filter does not skip.
+func (s *runState) moduleFiles(dir string) ([]string, error) {
+	return s.filter.ModuleFiles(s.roots[dir], dir)
+}
+
+// processFile sorts a single file, reached while walking root, or only checks it in check
+// mode, and records it for the module checks.
+func processFile(
+	ingestor *hclsort.Ingestor,
+	root, path, outputPath string,
+	opts *rootOptions,
+	state *runState,
+) error {
+	if opts.dryRun && rearrangesModules(opts) {
+		if err := state.planSort(ingestor, path); err != nil {
+			return err
+		}
+		state.record(root, path)
+		return nil
+	}
+	if !opts.check {
+		if err := ingestor.Parse(path, outputPath, opts.dryRun, false); err != nil {
+			return err
+		}
+		state.record(root, path)
+		return nil
+	}
+
+	_, sorted, err := ingestor.CheckSorted(path, false)
+	if err != nil {
+		return err
+	}
+	state.record(root, path)
+	if !sorted {
+		state.reportUnsorted(path)
+	}
+	return nil
+}
+
+// planSort keeps the change that sorting the file at path would make, to be printed as
+// a diff once the module changes are known.
+func (s *runState) planSort(ingestor *hclsort.Ingestor, path string) error {
+	src, err := hclsort.ReadFileBytes(hclsort.OSFileSystem{}, path)
+	if err != nil {
+		return err
+	}
+	sorted, err := ingestor.Sort(src, path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(src, sorted) {
+		s.sortChanges[path] = hclsort.FileChange{Path: path, Before: src, After: sorted}
+	}
+	return nil
+}
+
+// printSortChanges prints the planned sorting changes as unified diffs, in path order.
+func (s *runState) printSortChanges() {
+	paths := make([]string, 0, len(s.sortChanges))
+	for path := range s.sortChanges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Print(changeDiff(s.sortChanges[path]))
+	}
+}
+
+// checkStdin checks the content of stdin in check mode.
+func checkStdin(ingestor *hclsort.Ingestor, opts *rootOptions) error {
+	state := newRunState(nil)
+	file, sorted, err := ingestor.CheckSorted(hclsort.StdInPathIdentifier, true)
+	if err != nil {
+		return err
+	}
+	if !sorted {
+		state.reportUnsorted("stdin")
+	}
+
+	duplicates := (&hclsort.Module{Files: []*hclsort.ModuleFile{file}}).Duplicates()
+	for _, duplicate := range duplicates {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", duplicate)
+	}
+	return checkResult(opts, state, len(duplicates))
+}
+
+// reportUnsorted prints that the file at path is not sorted, once per file.
+func (s *runState) reportUnsorted(path string) {
+	if s.unsorted[path] {
+		return
+	}
+	s.unsorted[path] = true
+	fmt.Printf("%s is not sorted\n", path)
+}
+
+// reportDuplicates prints the duplicate declarations found among the processed files and
+// returns how many were found. In check mode, every module that contains a processed
+// file is checked as a whole.
+func reportDuplicates(ingestor *hclsort.Ingestor, opts *rootOptions, state *runState) int {
+	count := 0
+	for _, dir := range state.dirs() {
+		paths := state.processed[dir]
+		if opts.check {
+			var err error
+			if paths, err = state.moduleFiles(dir); err != nil || len(paths) == 0 {
+				continue
+			}
+		}
+		module, err := ingestor.LoadModule(paths)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping duplicate checks in %s: %v\n", dir, err)
+			continue
+		}
+		for _, duplicate := range module.Duplicates() {
+			// --merge-providers combines these entries instead.
+			if opts.mergeProviders != "" && duplicate.Kind == hclsort.RequiredProviderKind {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", duplicate)
+			count++
+		}
+	}
+	return count
+}
+
+// checkResult fails in check mode when unsorted files or duplicates were found.
+func checkResult(opts *rootOptions, state *runState, duplicates int) error {
+	if !opts.check || (len(state.unsorted) == 0 && duplicates == 0) {
+		return nil
+	}
+	return fmt.Errorf("check failed: %d file(s) not sorted, %d duplicate declaration(s)", len(state.unsorted), duplicates)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/AlexNabokikh/tfsort/internal/docs"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/spf13/cobra"
+)
+
+// newDocsCommand returns the command that documents the inputs and outputs of a module.
+func newDocsCommand(opts *rootOptions) *cobra.Command {
+	var format, readme string
+	cmd := &cobra.Command{
+		Use:   "docs <module-dir>",
+		Short: "Write tables of a module's inputs and outputs into its README.",
+		Long: "Render the variables and outputs of the module in <module-dir>, ordered by name, " +
+			"and write them between the " + docs.BeginMarker + " and " + docs.EndMarker +
+			" markers of its README.md, appending the markers if they are missing. With " +
+			"--dry-run, or with the json format, the result is printed instead.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			docFormat, err := docs.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+
+			paths, err := hclsort.ModuleFiles(args[0])
+			if err != nil {
+				return err
+			}
+			module, err := ingestor.LoadModule(paths)
+			if err != nil {
+				return err
+			}
+			content, err := docs.Render(docs.Collect(module), docFormat)
+			if err != nil {
+				return err
+			}
+
+			if opts.dryRun || docFormat == docs.FormatJSON {
+				fmt.Print(content)
+				return nil
+			}
+			if readme == "" {
+				readme = filepath.Join(args[0], "README.md")
+			}
+			return injectDocs(readme, content)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", string(docs.FormatMarkdown), "output format: markdown, asciidoc or json")
+	cmd.Flags().StringVar(&readme, "readme", "", "file to write the tables into (default README.md in <module-dir>)")
+	return cmd
+}
+
+// injectDocs writes content between the markers of the README at path, keeping its line
+// endings.
+func injectDocs(path, content string) error {
+	fsys := hclsort.OSFileSystem{}
+	original, err := fsys.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error reading file '%s': %w", path, err)
+	}
+
+	style := hclsort.DetectTextStyle(original)
+	updated, err := docs.Inject(style.Normalize(original), content)
+	if err != nil {
+		return fmt.Errorf("error updating '%s': %w", path, err)
+	}
+	updated = style.Restore(updated)
+
+	if string(updated) == string(original) {
+		fmt.Printf("%s is up to date\n", path)
+		return nil
+	}
+	if err = fsys.WriteFile(path, updated); err != nil {
+		return fmt.Errorf("error writing file '%s': %w", path, err)
+	}
+	fmt.Printf("Updated %s\n", path)
+	return nil
+}
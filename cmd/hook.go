@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlexNabokikh/tfsort/internal/git"
+	"github.com/AlexNabokikh/tfsort/internal/githook"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/spf13/cobra"
+)
+
+// runHookCommand is the hidden subcommand that the installed hook invokes.
+const runHookCommand = "run-hook"
+
+// newInstallHookCommand returns the command that installs or removes the pre-commit hook.
+func newInstallHookCommand(opts *rootOptions) *cobra.Command {
+	uninstall := false
+
+	cmd := &cobra.Command{
+		Use:   "install-hook",
+		Short: "Install a git pre-commit hook that sorts staged Terraform files.",
+		Long: "Install a git pre-commit hook in the current repository. The hook sorts the " +
+			"staged content of Terraform/HCL files and re-stages it, then runs the " +
+			"pre-commit hook that was installed before, if any. The --config, --symlinks, " +
+			"--line-endings, --no-format, --merge-locals, --include, --exclude, --gitignore " +
+			"and --include-overrides flags are passed on to the hook, and files ignored by " +
+			".tfsortignore are left alone. --merge-locals=module cannot be used, because the " +
+			"hook only changes staged files.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			hooksDir, err := git.HooksDir(".")
+			if err != nil {
+				return err
+			}
+
+			if uninstall {
+				if err = githook.Uninstall(hooksDir); err != nil {
+					return err
+				}
+				fmt.Printf("Removed %s hook from %s\n", githook.HookName, hooksDir)
+				return nil
+			}
+
+			command, err := hookCommand(opts)
+			if err != nil {
+				return err
+			}
+			if err = githook.Install(hooksDir, command); err != nil {
+				return err
+			}
+			fmt.Printf("Installed %s hook in %s\n", githook.HookName, hooksDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&uninstall, "uninstall", false, "remove the hook and restore the previous one")
+
+	return cmd
+}
+
+// newRunHookCommand returns the hidden command run by the installed hook.
+func newRunHookCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:    runHookCommand,
+		Short:  "Sort the staged Terraform files and re-stage them.",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+			filter, err := newPathFilter(ingestor, opts)
+			if err != nil {
+				return err
+			}
+
+			restaged, err := githook.SortStaged(".", ingestor, filter)
+			for _, path := range restaged {
+				fmt.Printf("tfsort: sorted and re-staged %s\n", path)
+			}
+			return err
+		},
+	}
+}
+
+// hookCommand returns the shell command the hook runs: this executable with the
+// flags that change the sorted output.
+func hookCommand(opts *rootOptions) (string, error) {
+	if opts.mergeLocals == mergeLocalsModule {
+		return "", errors.New("--merge-locals=module cannot be used with install-hook")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "tfsort"
+	}
+
+	words := []string{githook.ShellQuote(executable), runHookCommand}
+	if opts.configPath != "" {
+		// The hook runs in the top-level directory of the work tree.
+		configPath, absErr := filepath.Abs(opts.configPath)
+		if absErr != nil {
+			return "", fmt.Errorf("failed to resolve path '%s': %w", opts.configPath, absErr)
+		}
+		words = append(words, "--config", githook.ShellQuote(configPath))
+	}
+	if opts.symlinks != string(hclsort.SymlinkFollow) {
+		words = append(words, "--symlinks", githook.ShellQuote(opts.symlinks))
+	}
+	if opts.noFormat {
+		words = append(words, "--no-format")
+	}
+	if opts.lineEndings != string(hclsort.LineEndingsAuto) {
+		words = append(words, "--line-endings", githook.ShellQuote(opts.lineEndings))
+	}
+	for _, pattern := range opts.include {
+		words = append(words, "--include", githook.ShellQuote(pattern))
+	}
+	for _, pattern := range opts.exclude {
+		words = append(words, "--exclude", githook.ShellQuote(pattern))
+	}
+	if opts.mergeLocals != "" {
+		words = append(words, "--merge-locals="+githook.ShellQuote(opts.mergeLocals))
+	}
+	if opts.gitignore {
+		words = append(words, "--gitignore")
+	}
+	if opts.includeOverrides {
+		words = append(words, "--include-overrides")
+	}
+	return strings.Join(words, " "), nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/AlexNabokikh/tfsort/internal/config"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+// newLintCommand returns the command that checks modules against the lint rules.
+func newLintCommand(opts *rootOptions) *cobra.Command {
+	var fix bool
+	cmd := &cobra.Command{
+		Use:   "lint [paths...]",
+		Short: "Check modules against variable and output standards.",
+		Long: "Check every module that contains one of the given files, or a file found in " +
+			"the given directories, against the lint rules and print positioned diagnostics. " +
+			"Rule severities are read from " + config.DefaultFile + " in the working directory " +
+			"or the file given with --config. The command fails when a rule with severity " +
+			"error is violated. Without paths, the working directory is checked. With --fix, " +
+			"the arguments of module calls with a local source are put in the declaration " +
+			"order of the called module's variables when the module_arguments rule is enabled.",
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg, err := config.Load(opts.configPath)
+			if err != nil {
+				return err
+			}
+			linter, err := lint.New(cfg.RuleSeverities())
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+			return runLint(linter, args, fix, opts)
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "reorder the arguments of local module calls")
+	return cmd
+}
+
+// runLint lints the modules of the files selected by args and prints the diagnostics.
+// With fix, the arguments of local module calls are reordered afterwards.
+func runLint(linter *lint.Linter, args []string, fix bool, opts *rootOptions) error {
+	ingestor, err := newIngestor(opts)
+	if err != nil {
+		return err
+	}
+
+	filter, err := newPathFilter(ingestor, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			return errors.New("lint cannot read from stdin")
+		}
+	}
+
+	linter.Load = func(dir string) (*hclsort.Module, error) {
+		paths, dirErr := hclsort.ModuleFiles(dir)
+		if dirErr != nil {
+			return nil, dirErr
+		}
+		return ingestor.LoadModule(paths)
+	}
+	modules, pathErrors := collectLintModules(args, filter, opts)
+
+	errorCount := 0
+	for _, dir := range slices.Sorted(maps.Keys(modules)) {
+		module, loadErr := ingestor.LoadModule(modules[dir])
+		if loadErr != nil {
+			pathErrors = append(pathErrors, fmt.Errorf("error linting '%s': %w", dir, loadErr))
+			continue
+		}
+		for _, diagnostic := range linter.Lint(module) {
+			fmt.Println(diagnostic)
+			if diagnostic.Severity == lint.SeverityError {
+				errorCount++
+			}
+		}
+		if fix && linter.Enabled("module_arguments") {
+			pathErrors = append(pathErrors, fixModuleArguments(ingestor, module, linter.Load, opts)...)
+		}
+	}
+
+	if err = joinPathErrors(pathErrors); err != nil {
+		return err
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("lint failed: %d error(s)", errorCount)
+	}
+	return nil
+}
+
+// collectLintModules returns the module files that lint checks, by directory: the files
+// of the directories of the given files and of every directory below the given
+// directories. Files and directories skipped by the path filter are left out.
+func collectLintModules(args []string, filter *hclsort.PathFilter, opts *rootOptions) (map[string][]string, []error) {
+	modules := map[string][]string{}
+	var pathErrors []error
+	addDir := func(root, dir string) {
+		paths, err := hclsort.ModuleFiles(dir)
+		if err != nil {
+			pathErrors = append(pathErrors, err)
+			return
+		}
+		for _, path := range paths {
+			if skip, reason := filter.Skip(root, path, false); skip {
+				reportSkipped(opts, path, reason)
+				continue
+			}
+			if !slices.Contains(modules[dir], path) {
+				modules[dir] = append(modules[dir], path)
+			}
+		}
+	}
+
+	for _, arg := range args {
+		stat, err := os.Stat(arg)
+		if err != nil {
+			pathErrors = append(pathErrors, fmt.Errorf("failed to stat path: %w", err))
+			continue
+		}
+		if !stat.IsDir() {
+			addDir(".", filepath.Dir(arg))
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil || !d.IsDir() {
+				return walkErr
+			}
+			if path != arg && isToolDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			if skip, reason := filter.Skip(arg, path, true); skip {
+				reportSkipped(opts, path, reason)
+				return filepath.SkipDir
+			}
+			addDir(arg, path)
+			return nil
+		})
+		if err != nil {
+			pathErrors = append(pathErrors, fmt.Errorf("error walking directory '%s': %w", arg, err))
+		}
+	}
+	return modules, pathErrors
+}
+
+// fixModuleArguments reorders the arguments of the local module calls of module, or
+// prints the diff in dry-run mode.
+func fixModuleArguments(ingestor *hclsort.Ingestor, module *hclsort.Module, load lint.Loader, opts *rootOptions) []error {
+	orders := lint.ModuleArgumentOrders(module, load)
+	if len(orders) == 0 {
+		return nil
+	}
+
+	var errs []error
+	var changes []hclsort.FileChange
+	for _, file := range module.Files {
+		change, moved, err := ingestor.OrderModuleArguments(file.Path, orders)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case moved && opts.dryRun:
+			fmt.Print(changeDiff(change))
+		case moved:
+			changes = append(changes, change)
+		}
+	}
+	if err := applyChanges(changes); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/AlexNabokikh/tfsort/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// newLSPCommand returns the command that runs tfsort as a language server.
+func newLSPCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a Language Server Protocol server over stdio.",
+		Long: "Run a Language Server Protocol server over standard input and output. " +
+			"It supports document and range formatting, reports unsorted blocks as " +
+			"diagnostics and offers a quick fix that sorts them.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+			return lsp.NewServer(ingestor, os.Stdin, os.Stdout).Run()
+		},
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/AlexNabokikh/tfsort/internal/git"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/spf13/cobra"
+)
+
+// mergeVersionCount is the number of file versions a merge driver receives.
+const mergeVersionCount = 3
+
+// newMergeDriverCommand returns the command used as a git merge driver.
+func newMergeDriverCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge-driver <base> <current> <other> [path]",
+		Short: "Merge Terraform files block by block, for use as a git merge driver.",
+		Long: "Merge three versions of a Terraform/HCL file block by block and write the sorted " +
+			"result to <current>. Blocks are matched by type and labels, so additions on both " +
+			"sides never conflict. When the same block changed on both sides, or a version " +
+			"cannot be parsed, it falls back to git's line-based merge and reports the conflict.\n\n" +
+			"Configure it with:\n" +
+			"  git config merge.tfsort.driver \"tfsort merge-driver %O %A %B %P\"\n" +
+			"  echo '*.tf merge=tfsort' >> .gitattributes",
+		Args: cobra.RangeArgs(mergeVersionCount, mergeVersionCount+1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+
+			path := args[1]
+			if len(args) > mergeVersionCount {
+				path = args[mergeVersionCount]
+			}
+			return mergeDriver(ingestor, args[0], args[1], args[2], path)
+		},
+	}
+}
+
+// mergeDriver merges base, current and other into current. path is the name of the
+// merged file in the repository.
+func mergeDriver(ingestor *hclsort.Ingestor, base, current, other, path string) error {
+	versions := make([][]byte, 0, mergeVersionCount)
+	for _, name := range []string{base, current, other} {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("error reading file '%s': %w", name, err)
+		}
+		versions = append(versions, content)
+	}
+
+	merged, err := ingestor.Merge(versions[0], versions[1], versions[2], path)
+	if err == nil {
+		return (hclsort.OSFileSystem{}).WriteFile(current, merged)
+	}
+
+	var conflictErr *hclsort.MergeConflictError
+	if !errors.As(err, &conflictErr) {
+		fmt.Fprintf(os.Stderr, "tfsort: cannot merge %s block by block: %v\n", path, err)
+	}
+
+	conflicted, err := git.MergeFile(current, base, other)
+	if err != nil {
+		return err
+	}
+	if conflicted {
+		if conflictErr != nil {
+			return fmt.Errorf("merge conflict in %s: %w", path, conflictErr)
+		}
+		return fmt.Errorf("merge conflict in %s", path)
+	}
+	return nil
+}
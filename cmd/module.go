@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+)
+
+const (
+	// mergeLocalsFile merges the locals blocks of each file.
+	mergeLocalsFile = "file"
+	// mergeLocalsModule also moves the locals blocks of a module directory into one file.
+	mergeLocalsModule = "module"
+	// defaultProvidersFile is the file --merge-providers collects requirements into.
+	defaultProvidersFile = "versions.tf"
+)
+
+// rearrangesModules reports whether the run changes whole modules after sorting their
+// files, in which case dry runs print every change as a diff.
+func rearrangesModules(opts *rootOptions) bool {
+	return opts.mergeLocals == mergeLocalsModule || opts.mergeProviders != ""
+}
+
+// modulePlanner plans the changes to the files of the module in dir.
+type modulePlanner func(dir string, paths []string) ([]hclsort.FileChange, error)
+
+// rearrangeModules runs plan on every module that contains a processed file, over the
+// files of the module that the filter does not skip. Changes are reported in check mode,
+// printed as diffs in dry-run mode and written otherwise.
+func rearrangeModules(opts *rootOptions, state *runState, action string, plan modulePlanner) []error {
+	var errs []error
+	for _, dir := range state.dirs() {
+		paths, err := state.moduleFiles(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changes, err := plan(dir, paths)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error %s in '%s': %w", action, dir, err))
+			continue
+		}
+
+		switch {
+		case opts.check:
+			for _, change := range changes {
+				state.reportUnsorted(change.Path)
+			}
+		case opts.dryRun:
+			for _, change := range changes {
+				fmt.Print(changeDiff(change))
+				// The module change already includes the sorting of the file.
+				delete(state.sortChanges, change.Path)
+			}
+		default:
+			if err = applyChanges(changes); err != nil {
+				errs = append(errs, err)
+			}
+			state.forgetDeleted(changes)
+		}
+	}
+	return errs
+}
+
+// mergeModuleLocals merges the locals blocks of every module that contains a processed
+// file into one block.
+func mergeModuleLocals(ingestor *hclsort.Ingestor, opts *rootOptions, state *runState) []error {
+	return rearrangeModules(opts, state, "merging locals", func(_ string, paths []string) ([]hclsort.FileChange, error) {
+		return ingestor.MergeModuleLocals(paths)
+	})
+}
+
+// mergeModuleProviders collects the required providers of every module that contains a
+// processed file into the file named by --merge-providers, which must not be filtered out.
+func mergeModuleProviders(ingestor *hclsort.Ingestor, opts *rootOptions, state *runState) []error {
+	return rearrangeModules(opts, state, "merging required providers", func(dir string, paths []string) ([]hclsort.FileChange, error) {
+		target := filepath.Join(dir, opts.mergeProviders)
+		if skip, reason := state.filter.Skip(state.roots[dir], target, false); skip {
+			return nil, fmt.Errorf("cannot write to '%s', which is skipped: %s", target, reason)
+		}
+		return ingestor.MergeProviders(paths, target)
+	})
+}
@@ -9,33 +9,44 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/AlexNabokikh/tfsort/internal/config"
+	"github.com/AlexNabokikh/tfsort/internal/git"
 	"github.com/AlexNabokikh/tfsort/internal/hclsort"
 	"github.com/spf13/cobra"
 )
 
+// rootOptions holds the values of the root command's flags.
+type rootOptions struct {
+	outputPath   string
+	dryRun       bool
+	changedSince string
+	staged       bool
+	verbose      bool
+	gitignore    bool
+	symlinks     string
+	lineEndings  string
+	noFormat     bool
+	watch        bool
+	check        bool
+	configPath   string
+	// includeOverrides processes override files, which are skipped by default.
+	includeOverrides bool
+	mergeLocals      string
+	mergeProviders   string
+	include          []string
+	exclude          []string
+}
+
 // Execute is the entry point for the CLI.
 func Execute(version, commit, date string) {
-	var (
-		outputPath string
-		dryRun     bool
-	)
+	opts := &rootOptions{}
 
 	rootCmd := &cobra.Command{
 		Use:   "tfsort [flags] [files...]",
 		Short: "A utility to sort Terraform variables and outputs.",
 		Args:  cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return cmd.Help()
-			}
-
-			ingestor := hclsort.NewIngestor()
-			paths, err := argsToPaths(args)
-			if err != nil {
-				return err
-			}
-
-			return processPaths(ingestor, paths, dryRun, outputPath)
+			return runRoot(cmd, args, opts)
 		},
 	}
 
@@ -61,23 +72,251 @@ func Execute(version, commit, date string) {
 		}
 	}
 
-	rootCmd.PersistentFlags().StringVarP(
-		&outputPath,
+	bindRootFlags(rootCmd, opts)
+	rootCmd.AddCommand(
+		newLSPCommand(opts),
+		newInstallHookCommand(opts),
+		newRunHookCommand(opts),
+		newMergeDriverCommand(opts),
+		newSplitCommand(opts),
+		newLintCommand(opts),
+		newDocsCommand(opts),
+		newTfvarsCommand(opts),
+		newScaffoldVarsCommand(opts),
+	)
+
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// bindRootFlags registers the root command's flags on cmd.
+func bindRootFlags(cmd *cobra.Command, opts *rootOptions) {
+	flags := cmd.PersistentFlags()
+
+	flags.StringVarP(
+		&opts.outputPath,
 		"out",
 		"o",
 		"",
 		"path to the output file (cannot be used when path is used as an argument)",
 	)
-	rootCmd.PersistentFlags().BoolVarP(
-		&dryRun,
+	flags.BoolVarP(
+		&opts.dryRun,
 		"dry-run",
 		"d", false,
 		"preview the changes without altering the original file(s).",
 	)
+	flags.StringVar(
+		&opts.changedSince,
+		"changed-since",
+		"",
+		"only process files added or modified relative to the given git ref",
+	)
+	flags.BoolVar(
+		&opts.staged,
+		"staged",
+		false,
+		"only process files added or modified in the git index",
+	)
+	cmd.MarkFlagsMutuallyExclusive("changed-since", "staged")
+	flags.StringArrayVar(
+		&opts.include,
+		"include",
+		nil,
+		"only process files matching this glob (doublestar syntax, repeatable)",
+	)
+	flags.StringArrayVar(
+		&opts.exclude,
+		"exclude",
+		nil,
+		"skip files and directories matching this glob (doublestar syntax, repeatable)",
+	)
+	flags.BoolVar(
+		&opts.gitignore,
+		"gitignore",
+		false,
+		"skip files and directories ignored by .gitignore rules",
+	)
+	flags.StringVar(
+		&opts.symlinks,
+		"symlinks",
+		string(hclsort.SymlinkFollow),
+		"how to rewrite symlinked files in place: follow, skip or error",
+	)
+	flags.StringVar(
+		&opts.lineEndings,
+		"line-endings",
+		string(hclsort.LineEndingsAuto),
+		"line endings of written files: auto (keep the input's), lf or crlf",
+	)
+	flags.StringVar(
+		&opts.configPath,
+		"config",
+		"",
+		"path to the configuration file (default "+config.DefaultFile+")",
+	)
+	flags.BoolVar(
+		&opts.includeOverrides,
+		"include-overrides",
+		false,
+		"also sort override files (override.tf, *_override.tf), which are skipped by default",
+	)
+	flags.BoolVar(
+		&opts.noFormat,
+		"no-format",
+		false,
+		"only reorder blocks and attributes, leaving all other formatting untouched",
+	)
+	flags.BoolVar(
+		&opts.watch,
+		"watch",
+		false,
+		"keep running and sort files in the given directories whenever they change",
+	)
+	cmd.MarkFlagsMutuallyExclusive("watch", "changed-since")
+	cmd.MarkFlagsMutuallyExclusive("watch", "staged")
+	cmd.MarkFlagsMutuallyExclusive("watch", "out")
+	cmd.MarkFlagsMutuallyExclusive("watch", "dry-run")
+	flags.BoolVar(
+		&opts.check,
+		"check",
+		false,
+		"report unsorted files and duplicate declarations without changing anything, and fail if any are found",
+	)
+	cmd.MarkFlagsMutuallyExclusive("check", "out")
+	cmd.MarkFlagsMutuallyExclusive("check", "dry-run")
+	cmd.MarkFlagsMutuallyExclusive("check", "watch")
+	flags.StringVar(
+		&opts.mergeLocals,
+		"merge-locals",
+		"",
+		"combine all locals blocks into one sorted block per file, or per module directory with module",
+	)
+	flags.Lookup("merge-locals").NoOptDefVal = mergeLocalsFile
+	flags.StringVar(
+		&opts.mergeProviders,
+		"merge-providers",
+		"",
+		"collect the required providers of each module directory into one terraform block in the given file",
+	)
+	flags.Lookup("merge-providers").NoOptDefVal = defaultProvidersFile
+	flags.BoolVar(
+		&opts.verbose,
+		"verbose",
+		false,
+		"report skipped files and directories",
+	)
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+// runRoot sorts the files selected by args or by the git flags.
+func runRoot(cmd *cobra.Command, args []string, opts *rootOptions) error {
+	useGit := opts.changedSince != "" || opts.staged
+	if len(args) == 0 && !useGit {
+		return cmd.Help()
+	}
+
+	ingestor, err := newIngestor(opts)
+	if err != nil {
+		return err
+	}
+
+	filter, err := newPathFilter(ingestor, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.mergeLocals == mergeLocalsModule && (opts.watch || opts.outputPath != "") {
+		return errors.New("--merge-locals=module cannot be used with --watch or --out")
+	}
+	if opts.mergeProviders != "" && (opts.watch || opts.outputPath != "") {
+		return errors.New("--merge-providers cannot be used with --watch or --out")
+	}
+	if opts.mergeProviders != "" && filepath.Base(opts.mergeProviders) != opts.mergeProviders {
+		return fmt.Errorf("invalid merge-providers file '%s': must be a file name", opts.mergeProviders)
+	}
+
+	if opts.watch {
+		return runWatch(ingestor, filter, args)
+	}
+
+	if useGit {
+		if opts.outputPath != "" {
+			return errors.New("--out cannot be used with --changed-since or --staged")
+		}
+
+		paths, changedErr := changedPaths(ingestor, args, opts.changedSince, opts.staged)
+		if changedErr != nil {
+			return changedErr
+		}
+		if len(paths) == 0 {
+			fmt.Println("No changed Terraform/HCL files found.")
+			return nil
+		}
+
+		return processPaths(ingestor, filter, paths, opts)
 	}
+
+	paths, err := argsToPaths(args)
+	if err != nil {
+		return err
+	}
+
+	return processPaths(ingestor, filter, paths, opts)
+}
+
+// newIngestor builds an Ingestor configured by the root command's flags.
+func newIngestor(opts *rootOptions) (*hclsort.Ingestor, error) {
+	symlinkPolicy, err := hclsort.ParseSymlinkPolicy(opts.symlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	lineEndings, err := hclsort.ParseLineEndings(opts.lineEndings)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.mergeLocals {
+	case "", mergeLocalsFile, mergeLocalsModule:
+	default:
+		return nil, fmt.Errorf("invalid merge-locals scope '%s': must be one of file, module", opts.mergeLocals)
+	}
+
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := cfg.SortProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	ingestor := hclsort.NewIngestor()
+	ingestor.Profiles = profiles
+	for _, profile := range profiles {
+		for _, ext := range profile.Extensions() {
+			ingestor.AllowedTypes[ext] = true
+		}
+	}
+	ingestor.MergeLocals = opts.mergeLocals != ""
+	ingestor.SymlinkPolicy = symlinkPolicy
+	ingestor.LineEndings = lineEndings
+	ingestor.NoFormat = opts.noFormat
+	ingestor.IncludeOverrides = opts.includeOverrides
+
+	return ingestor, nil
+}
+
+// newPathFilter builds the PathFilter configured by the root command's flags, which
+// warns through the ingestor's logger.
+func newPathFilter(ingestor *hclsort.Ingestor, opts *rootOptions) (*hclsort.PathFilter, error) {
+	filter, err := hclsort.NewPathFilter(opts.include, opts.exclude, opts.gitignore)
+	if err != nil {
+		return nil, err
+	}
+	filter.Logger = ingestor.Logger
+	return filter, nil
 }
 
 func argsToPaths(args []string) ([]string, error) {
@@ -95,18 +334,127 @@ func argsToPaths(args []string) ([]string, error) {
 	return args, nil
 }
 
+// changedPaths asks the local git repository for added or modified files and keeps
+// the ones with a supported extension that live under one of the given paths.
+func changedPaths(
+	ingestor *hclsort.Ingestor,
+	args []string,
+	ref string,
+	staged bool,
+) ([]string, error) {
+	var (
+		files []string
+		err   error
+	)
+	if staged {
+		files, err = git.StagedFiles(".")
+	} else {
+		files, err = git.ChangedFiles(".", ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]string, 0, len(args))
+	for _, arg := range args {
+		abs, absErr := filepath.Abs(arg)
+		if absErr != nil {
+			return nil, fmt.Errorf("failed to resolve path '%s': %w", arg, absErr)
+		}
+		scopes = append(scopes, abs)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	paths := []string{}
+	for _, file := range files {
+		fileExtension := strings.TrimPrefix(filepath.Ext(file), ".")
+		if !ingestor.AllowedTypes[fileExtension] || !withinAny(file, scopes) {
+			continue
+		}
+
+		if rel, relErr := filepath.Rel(cwd, file); relErr == nil {
+			file = rel
+		}
+		paths = append(paths, file)
+	}
+
+	return paths, nil
+}
+
+// withinAny reports whether path equals or is nested under one of scopes.
+// An empty scope list matches every path.
+func withinAny(path string, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		rel, err := filepath.Rel(scope, path)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // processPaths processes the provided paths, handling both files and directories.
 // It will walk through directories recursively.
 func processPaths(
 	ingestor *hclsort.Ingestor,
+	filter *hclsort.PathFilter,
 	paths []string,
-	dryRun bool,
-	outputPath string,
+	opts *rootOptions,
 ) error {
 	if len(paths) == 1 && paths[0] == hclsort.StdInPathIdentifier {
-		return ingestor.Parse(paths[0], outputPath, dryRun, true)
+		if opts.check {
+			return checkStdin(ingestor, opts)
+		}
+		return ingestor.Parse(paths[0], opts.outputPath, opts.dryRun, true)
+	}
+
+	state := newRunState(filter)
+	pathErrors := walkPaths(ingestor, paths, opts, state)
+
+	if opts.mergeLocals == mergeLocalsModule {
+		pathErrors = append(pathErrors, mergeModuleLocals(ingestor, opts, state)...)
+	}
+	if opts.mergeProviders != "" {
+		pathErrors = append(pathErrors, mergeModuleProviders(ingestor, opts, state)...)
 	}
+	state.printSortChanges()
 
+	duplicates := reportDuplicates(ingestor, opts, state)
+
+	if err := joinPathErrors(pathErrors); err != nil {
+		return err
+	}
+
+	return checkResult(opts, state, duplicates)
+}
+
+// joinPathErrors combines the errors of the paths that could not be processed.
+func joinPathErrors(pathErrors []error) error {
+	if len(pathErrors) == 0 {
+		return nil
+	}
+	errStrings := make([]string, len(pathErrors))
+	for i, e := range pathErrors {
+		errStrings[i] = e.Error()
+	}
+	return fmt.Errorf("could not process all paths:\n%s", strings.Join(errStrings, "\n"))
+}
+
+// walkPaths processes the given files and the files found in the given directories,
+// records them in state and returns the errors of the files that could not be processed.
+func walkPaths(ingestor *hclsort.Ingestor, paths []string, opts *rootOptions, state *runState) []error {
 	pathErrors := []error{}
 	for _, path := range paths {
 		stat, statErr := os.Stat(path)
@@ -117,7 +465,7 @@ func processPaths(
 
 		if stat.IsDir() {
 			// Recursive
-			err := filepath.WalkDir(path, newWalkDirCallback(ingestor, dryRun))
+			err := filepath.WalkDir(path, newWalkDirCallback(ingestor, path, opts, state))
 			if err != nil {
 				pathErrors = append(pathErrors, fmt.Errorf("error walking directory '%s': %w", path, err))
 			}
@@ -128,29 +476,33 @@ func processPaths(
 				continue
 			}
 
-			err := ingestor.Parse(path, outputPath, dryRun, false)
+			if skip, reason := state.filter.Skip(".", path, false); skip {
+				reportSkipped(opts, path, reason)
+				continue
+			}
+			if skipFile(ingestor, path) {
+				continue
+			}
+
+			err := processFile(ingestor, ".", path, opts.outputPath, opts, state)
 			if err != nil {
 				pathErrors = append(pathErrors, fmt.Errorf("error processing file '%s': %w", path, err))
 			}
 		}
 	}
 
-	if len(pathErrors) > 0 {
-		errStrings := make([]string, len(pathErrors))
-		for i, e := range pathErrors {
-			errStrings[i] = e.Error()
-		}
-		return fmt.Errorf("could not process all paths:\n%s", strings.Join(errStrings, "\n"))
-	}
-
-	return nil
+	return pathErrors
 }
 
 // newWalkDirCallback creates a callback function for filepath.WalkDir.
 func newWalkDirCallback(
 	ingestor *hclsort.Ingestor,
-	isDryRun bool,
+	root string,
+	opts *rootOptions,
+	state *runState,
 ) fs.WalkDirFunc {
+	isDryRun := opts.dryRun || opts.check
+
 	return func(currentPath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			fmt.Fprintf(
@@ -167,16 +519,18 @@ func newWalkDirCallback(
 		}
 
 		if d.IsDir() {
-			dirName := d.Name()
-			if dirName == ".git" ||
-				dirName == ".terraform" ||
-				dirName == ".terragrunt-cache" {
+			if isToolDir(d.Name()) {
 				if !isDryRun {
 					fmt.Printf("Skipping directory: %s\n", currentPath)
 				}
 				return filepath.SkipDir
 			}
 
+			if skip, reason := state.filter.Skip(root, currentPath, true); skip {
+				reportSkipped(opts, currentPath, reason)
+				return filepath.SkipDir
+			}
+
 			return nil
 		}
 
@@ -185,10 +539,19 @@ func newWalkDirCallback(
 			return nil
 		}
 
+		if skip, reason := state.filter.Skip(root, currentPath, false); skip {
+			reportSkipped(opts, currentPath, reason)
+			return nil
+		}
+
+		if skipFile(ingestor, currentPath) {
+			return nil
+		}
+
 		if !isDryRun {
 			fmt.Printf("Processing %s...\n", currentPath)
 		}
-		err = ingestor.Parse(currentPath, "", isDryRun, false)
+		err = processFile(ingestor, root, currentPath, "", opts, state)
 		if err != nil {
 			fmt.Fprintf(
 				os.Stderr,
@@ -201,6 +564,32 @@ func newWalkDirCallback(
 	}
 }
 
+// isToolDir reports whether name is a version control or Terraform utility directory,
+// which is never processed.
+func isToolDir(name string) bool {
+	return name == ".git" || name == ".terraform" || name == ".terragrunt-cache"
+}
+
+// skipFile reports whether the ingestor leaves the file at path alone, and warns about it.
+func skipFile(ingestor *hclsort.Ingestor, path string) bool {
+	skip, reason := ingestor.SkipFile(path)
+	if !skip {
+		return false
+	}
+	if hclsort.IsOverrideFile(path) {
+		reason += " (use --include-overrides to sort it)"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: skipping %s: %s\n", path, reason)
+	return true
+}
+
+// reportSkipped prints why path was skipped when verbose output is enabled.
+func reportSkipped(opts *rootOptions, path, reason string) {
+	if opts.verbose {
+		fmt.Fprintf(os.Stderr, "Skipping %s: %s\n", path, reason)
+	}
+}
+
 // useStdin determines whether to read stdin.
 func useStdin() (bool, error) {
 	stat, statErr := os.Stdin.Stat()
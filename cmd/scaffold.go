@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+// newScaffoldVarsCommand returns the command that declares referenced but undeclared
+// variables.
+func newScaffoldVarsCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "scaffold-vars <dir>",
+		Short: "Add variable blocks for var.* references that are not declared.",
+		Long: "Find the var.<name> references of the module in <dir> that have no variable " +
+			"block and add a stub for each to variables.tf, or to variables.tofu when it exists " +
+			"or the module is written in .tofu files, with an empty description and a type " +
+			"guessed from how the variable is used. The stubs are sorted into place. " +
+			"With --dry-run, a diff is printed instead.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+
+			paths, err := hclsort.ModuleFiles(args[0])
+			if err != nil {
+				return err
+			}
+			module, err := ingestor.LoadModule(paths)
+			if err != nil {
+				return err
+			}
+
+			undeclared := lint.UndeclaredVariables(module)
+			if len(undeclared) == 0 {
+				fmt.Println("No undeclared variables.")
+				return nil
+			}
+
+			target := hclsort.TargetFile(args[0], "variables", paths)
+			change, err := ingestor.AppendBlocks(target, variableStubs(undeclared))
+			if err != nil {
+				return err
+			}
+			if opts.dryRun {
+				fmt.Print(changeDiff(change))
+				return nil
+			}
+			for _, v := range undeclared {
+				fmt.Printf("Declared variable %q (%s)\n", v.Name, v.Type)
+			}
+			return applyChanges([]hclsort.FileChange{change})
+		},
+	}
+}
+
+// variableStubs returns a variable block for each undeclared variable.
+func variableStubs(undeclared []lint.UndeclaredVariable) []byte {
+	var buf strings.Builder
+	for _, v := range undeclared {
+		fmt.Fprintf(&buf, "variable %q {\n  description = \"\"\n  type        = %s\n}\n\n", v.Name, v.Type)
+	}
+	return []byte(buf.String())
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlexNabokikh/tfsort/internal/diff"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/spf13/cobra"
+)
+
+// newSplitCommand returns the command that moves blocks into conventional files.
+func newSplitCommand(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "split <dir>",
+		Short: "Move variables, outputs and version constraints into their conventional files.",
+		Long: "Move every variable block of the module in <dir> into variables.tf, every output " +
+			"block into outputs.tf and the required_version and required_providers of terraform " +
+			"blocks into versions.tf (or the .tofu equivalents). Backend, cloud and other " +
+			"terraform settings stay where they are, and override files are left alone. Moved " +
+			"blocks keep their comments, changed files are sorted and files left empty are " +
+			"deleted. With --dry-run, a diff of every file is printed instead.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+
+			paths, err := hclsort.ModuleFiles(args[0])
+			if err != nil {
+				return err
+			}
+
+			changes, err := ingestor.Split(paths)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				fmt.Println("Nothing to split.")
+				return nil
+			}
+
+			if opts.dryRun {
+				for _, change := range changes {
+					fmt.Print(changeDiff(change))
+				}
+				return nil
+			}
+			return applyChanges(changes)
+		},
+	}
+}
+
+// changeDiff renders a planned change as a unified diff.
+func changeDiff(change hclsort.FileChange) string {
+	from, to := change.Path, change.Path
+	if change.Before == nil {
+		from = os.DevNull
+	}
+	if change.After == nil {
+		to = os.DevNull
+	}
+	return diff.Unified(from, to, change.Before, change.After)
+}
+
+// applyChanges writes the changed files first and deletes the emptied ones last, so
+// that no block is lost if a write fails.
+func applyChanges(changes []hclsort.FileChange) error {
+	fsys := hclsort.OSFileSystem{}
+	for _, change := range changes {
+		if change.After == nil {
+			continue
+		}
+		if err := fsys.WriteFile(change.Path, change.After); err != nil {
+			return fmt.Errorf("error writing file '%s': %w", change.Path, err)
+		}
+		if change.Before == nil {
+			fmt.Printf("Created %s\n", change.Path)
+		} else {
+			fmt.Printf("Updated %s\n", change.Path)
+		}
+	}
+
+	for _, change := range changes {
+		if change.After != nil {
+			continue
+		}
+		if err := os.Remove(change.Path); err != nil {
+			return fmt.Errorf("error deleting file '%s': %w", change.Path, err)
+		}
+		fmt.Printf("Deleted %s\n", change.Path)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/AlexNabokikh/tfsort/internal/diff"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/tfvars"
+	"github.com/spf13/cobra"
+)
+
+// tfvarsSyncArgs is the number of arguments of tfvars sync: the module and the file.
+const tfvarsSyncArgs = 2
+
+// newTfvarsCommand returns the command group for variable definitions files.
+func newTfvarsCommand(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tfvars",
+		Short: "Work with variable definitions (.tfvars) files.",
+	}
+	cmd.AddCommand(newTfvarsSyncCommand(opts))
+	return cmd
+}
+
+// newTfvarsSyncCommand returns the command that syncs a .tfvars file with a module.
+func newTfvarsSyncCommand(opts *rootOptions) *cobra.Command {
+	var syncOpts tfvars.Options
+	cmd := &cobra.Command{
+		Use:   "sync <module-dir> <file.tfvars>",
+		Short: "Order a .tfvars file like the module's variables and report stale or missing keys.",
+		Long: "Compare the keys of <file.tfvars> with the variable blocks of the module in " +
+			"<module-dir>. Keys are written in the order the variables are declared. Keys " +
+			"without a variable are reported, or removed with --remove-stale, and required " +
+			"variables without a key are reported, or added as commented placeholders with " +
+			"--add-missing. With --dry-run, a diff is printed instead.",
+		Args: cobra.ExactArgs(tfvarsSyncArgs),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ingestor, err := newIngestor(opts)
+			if err != nil {
+				return err
+			}
+			syncOpts.NoFormat = opts.noFormat
+			return syncTfvars(ingestor, args[0], args[1], syncOpts, opts.dryRun)
+		},
+	}
+	cmd.Flags().BoolVar(&syncOpts.RemoveStale, "remove-stale", false, "remove keys that no variable declares")
+	cmd.Flags().BoolVar(&syncOpts.AddMissing, "add-missing", false,
+		"add commented placeholders for required variables without a key")
+	return cmd
+}
+
+// syncTfvars syncs the file at path with the module in dir and reports what differs.
+func syncTfvars(ingestor *hclsort.Ingestor, dir, path string, syncOpts tfvars.Options, dryRun bool) error {
+	paths, err := hclsort.ModuleFiles(dir)
+	if err != nil {
+		return err
+	}
+	module, err := ingestor.LoadModule(paths)
+	if err != nil {
+		return err
+	}
+
+	fsys := hclsort.OSFileSystem{}
+	original, err := hclsort.ReadFileBytes(fsys, path)
+	if err != nil {
+		return err
+	}
+	style := hclsort.DetectTextStyle(original).WithLineEndings(ingestor.LineEndings)
+	result, err := tfvars.Sync(module, style.Normalize(original), path, syncOpts)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range result.Stale {
+		verb := "is not declared by any variable"
+		if syncOpts.RemoveStale {
+			verb = "removed: it is not declared by any variable"
+		}
+		fmt.Fprintf(os.Stderr, "%s:%d,%d: key %q %s\n", path, key.Range.Start.Line, key.Range.Start.Column, key.Name, verb)
+	}
+	for _, name := range result.Missing {
+		fmt.Fprintf(os.Stderr, "%s: required variable %q has no value\n", path, name)
+	}
+
+	updated := style.Restore(result.Content)
+	switch {
+	case bytes.Equal(updated, original):
+		return nil
+	case dryRun:
+		fmt.Print(diff.Unified(path, path, original, updated))
+		return nil
+	}
+	if err = fsys.WriteFile(path, updated); err != nil {
+		return fmt.Errorf("error writing file '%s': %w", path, err)
+	}
+	fmt.Printf("Updated %s\n", path)
+	return nil
+}
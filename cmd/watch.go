@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/watch"
+)
+
+// fileStamp identifies the version of a file that tfsort wrote.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// runWatch sorts files under the given directories whenever they change, until the
+// process is interrupted.
+func runWatch(ingestor *hclsort.Ingestor, filter *hclsort.PathFilter, dirs []string) error {
+	for _, dir := range dirs {
+		stat, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("failed to stat path: %w", err)
+		}
+		if !stat.IsDir() {
+			return fmt.Errorf("--watch requires directories, but '%s' is a file", dir)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	written := map[string]fileStamp{}
+	watcher := watch.New(dirs, newWatchFilter(ingestor, filter))
+
+	fmt.Printf("Watching %s for changes. Press Ctrl+C to stop.\n", strings.Join(dirs, ", "))
+	return watcher.Run(ctx, func(path string) {
+		sortChangedFile(ingestor, path, written)
+	})
+}
+
+// newWatchFilter skips the same directories and files as the directory walk.
+func newWatchFilter(ingestor *hclsort.Ingestor, filter *hclsort.PathFilter) watch.Filter {
+	return func(root, path string, isDir bool) bool {
+		if isDir && isToolDir(filepath.Base(path)) {
+			return true
+		}
+		if !isDir && !ingestor.AllowedTypes[strings.TrimPrefix(filepath.Ext(path), ".")] {
+			return true
+		}
+
+		skip, _ := filter.Skip(root, path, isDir)
+		return skip
+	}
+}
+
+// sortChangedFile sorts path in place and prints one line if it was rewritten.
+// Changes caused by tfsort's own writes are ignored.
+func sortChangedFile(ingestor *hclsort.Ingestor, path string, written map[string]fileStamp) {
+	if stamp, ok := written[path]; ok && stamp == stampOf(path) {
+		return
+	}
+
+	changed, err := ingestor.SortFile(path)
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error sorting file %s: %v\n", path, err)
+	case changed:
+		written[path] = stampOf(path)
+		fmt.Printf("Sorted %s\n", path)
+	}
+}
+
+// stampOf returns the current stamp of path, or the zero stamp if it cannot be read.
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}
+}
@@ -0,0 +1,147 @@
+// Package config loads the .tfsort.hcl configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// DefaultFile is the configuration file read from the working directory when no other
+// file is given.
+const DefaultFile = ".tfsort.hcl"
+
+// Config is the content of a configuration file.
+type Config struct {
+	Lint     *Lint     `hcl:"lint,block"`
+	Profiles []Profile `hcl:"profile,block"`
+}
+
+// Lint configures the lint command.
+type Lint struct {
+	Rules []Rule `hcl:"rule,block"`
+}
+
+// Rule overrides the settings of a single lint rule.
+type Rule struct {
+	Name     string `hcl:"name,label"`
+	Severity string `hcl:"severity"`
+}
+
+// Profile defines a sorting profile, or extends the built-in profile with the same name.
+type Profile struct {
+	Name string `hcl:"name,label"`
+	// Files are the base name patterns of the files that use the profile.
+	Files   []string   `hcl:"files,optional"`
+	InPlace *bool      `hcl:"in_place,optional"`
+	Rules   []SortRule `hcl:"rule,block"`
+}
+
+// SortRule says how the blocks at a block path, such as "job > group > task", are sorted.
+type SortRule struct {
+	Path           string   `hcl:"path,label"`
+	SortBy         []string `hcl:"sort_by,optional"`
+	InPlace        bool     `hcl:"in_place,optional"`
+	SortAttributes bool     `hcl:"sort_attributes,optional"`
+}
+
+// Load reads the configuration file at path. An empty path reads DefaultFile, and
+// yields an empty configuration when that file does not exist.
+func Load(path string) (*Config, error) {
+	optional := path == ""
+	if optional {
+		path = DefaultFile
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("error reading config file '%s': %w", path, err)
+	}
+	return Parse(src, path)
+}
+
+// Parse decodes the configuration in src, read from filename.
+func Parse(src []byte, filename string) (*Config, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("error parsing config file '%s': %w", filename, diags)
+	}
+
+	cfg := &Config{}
+	if diags = gohcl.DecodeBody(file.Body, nil, cfg); diags.HasErrors() {
+		return nil, fmt.Errorf("error decoding config file '%s': %w", filename, diags)
+	}
+	return cfg, nil
+}
+
+// RuleSeverities returns the severity configured for each lint rule, by rule name.
+func (c *Config) RuleSeverities() map[string]string {
+	severities := map[string]string{}
+	if c.Lint != nil {
+		for _, rule := range c.Lint.Rules {
+			severities[rule.Name] = rule.Severity
+		}
+	}
+	return severities
+}
+
+// SortProfiles returns the configured sorting profiles. A profile named like a built-in
+// one extends it: its rules take precedence over the built-in rules for the same path,
+// and its files and in_place setting replace the built-in ones when set. Other profiles
+// must list their files.
+func (c *Config) SortProfiles() ([]hclsort.Profile, error) {
+	profiles := make([]hclsort.Profile, 0, len(c.Profiles))
+	for _, cfg := range c.Profiles {
+		profile, builtin := hclsort.BuiltinProfile(cfg.Name)
+		profile.Name = cfg.Name
+		if len(cfg.Files) > 0 {
+			profile.Files = cfg.Files
+		} else if !builtin && cfg.Name != hclsort.ProfileTerraform {
+			return nil, fmt.Errorf("profile '%s' must list its files", cfg.Name)
+		}
+		if cfg.InPlace != nil {
+			profile.InPlace = *cfg.InPlace
+		}
+
+		rules := make([]hclsort.BlockRule, 0, len(cfg.Rules)+len(profile.Rules))
+		for _, ruleCfg := range cfg.Rules {
+			rule, err := ruleCfg.blockRule()
+			if err != nil {
+				return nil, fmt.Errorf("profile '%s': %w", cfg.Name, err)
+			}
+			rules = append(rules, rule)
+		}
+		profile.Rules = append(rules, profile.Rules...)
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// blockRule converts the rule into the form used for sorting.
+func (r SortRule) blockRule() (hclsort.BlockRule, error) {
+	path, err := hclsort.ParseBlockPath(r.Path)
+	if err != nil {
+		return hclsort.BlockRule{}, err
+	}
+	rule := hclsort.BlockRule{Path: path, InPlace: r.InPlace, SortAttributes: r.SortAttributes}
+	for _, value := range r.SortBy {
+		part, partErr := hclsort.ParseKeyPart(value)
+		if partErr != nil {
+			return hclsort.BlockRule{}, fmt.Errorf("rule '%s': %w", r.Path, partErr)
+		}
+		rule.SortBy = append(rule.SortBy, part)
+	}
+	if len(path) == 0 && len(rule.SortBy) > 0 {
+		return hclsort.BlockRule{}, errors.New("the top-level rule can only sort attributes")
+	}
+	return rule, nil
+}
@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/config"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	src := "lint {\n  rule \"variable_type\" {\n    severity = \"error\"\n  }\n" +
+		"  rule \"snake_case\" {\n    severity = \"off\"\n  }\n}\n"
+	cfg, err := config.Parse([]byte(src), config.DefaultFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := map[string]string{"variable_type": "error", "snake_case": "off"}
+	if diff := cmp.Diff(want, cfg.RuleSeverities()); diff != "" {
+		t.Errorf("unexpected severities (-want +got):\n%s", diff)
+	}
+
+	if _, err = config.Parse([]byte("lint {\n  unknown = true\n}\n"), config.DefaultFile); err == nil {
+		t.Error("expected an error for an unknown attribute")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custom.hcl")
+	if err := os.WriteFile(path, []byte("lint {\n  rule \"variable_type\" {\n    severity = \"off\"\n  }\n}\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"variable_type": "off"}, cfg.RuleSeverities()); diff != "" {
+		t.Errorf("unexpected severities (-want +got):\n%s", diff)
+	}
+
+	if _, err = config.Load(filepath.Join(dir, "missing.hcl")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestSortProfiles(t *testing.T) {
+	t.Parallel()
+
+	src := "profile \"consul\" {\n  files = [\"*.consul.hcl\"]\n\n  rule \"service > check\" {\n" +
+		"    sort_by         = [\"attribute.name\", \"label.0\"]\n    sort_attributes = true\n  }\n}\n\n" +
+		"profile \"packer\" {\n  in_place = false\n\n  rule \"source\" {}\n}\n"
+	cfg, err := config.Parse([]byte(src), config.DefaultFile)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	profiles, err := cfg.SortProfiles()
+	if err != nil {
+		t.Fatalf("SortProfiles failed: %v", err)
+	}
+
+	packer := hclsort.PackerProfile()
+	packer.InPlace = false
+	packer.Rules = append([]hclsort.BlockRule{{Path: []string{"source"}}}, packer.Rules...)
+	want := []hclsort.Profile{
+		{
+			Name:  "consul",
+			Files: []string{"*.consul.hcl"},
+			Rules: []hclsort.BlockRule{{
+				Path:           []string{"service", "check"},
+				SortBy:         []hclsort.KeyPart{{Attribute: "name"}, {Label: 0}},
+				SortAttributes: true,
+			}},
+		},
+		packer,
+	}
+	if diff := cmp.Diff(want, profiles); diff != "" {
+		t.Errorf("unexpected profiles (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{
+		"profile \"custom\" {}\n",
+		"profile \"custom\" {\n  files = [\"*.x\"]\n\n  rule \"a\" {\n    sort_by = [\"label\"]\n  }\n}\n",
+		"profile \"custom\" {\n  files = [\"*.x\"]\n\n  rule \"a >\" {}\n}\n",
+		"profile \"custom\" {\n  files = [\"*.x\"]\n\n  rule \"\" {\n    sort_by = [\"label.0\"]\n  }\n}\n",
+	} {
+		cfg, err = config.Parse([]byte(bad), config.DefaultFile)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if _, err = cfg.SortProfiles(); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
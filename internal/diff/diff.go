@@ -0,0 +1,158 @@
+// Package diff renders line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change.
+const contextLines = 3
+
+// op is a single line of an edit script: kept (' '), deleted ('-') or inserted ('+').
+type op struct {
+	kind byte
+	line string
+	// aPos and bPos are the numbers of lines of a and b before this line.
+	aPos int
+	bPos int
+}
+
+// Unified returns the unified diff that turns a into b, labelled with fromName and
+// toName. It is empty when a and b are equal.
+func Unified(fromName, toName string, a, b []byte) string {
+	ops := editScript(splitLines(string(a)), splitLines(string(b)))
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		end := hunkEnd(ops, start)
+		writeHunk(&out, ops[max(0, start-contextLines):end])
+		start = end
+	}
+	return out.String()
+}
+
+// splitLines splits s after every newline. The last line has no newline if s does not
+// end with one.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns a shortest edit script from a to b, based on their longest common
+// subsequence of lines.
+func editScript(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of am[i:] and bm[j:].
+	lcs := make([][]int, len(am)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bm)+1)
+	}
+	for i := len(am) - 1; i >= 0; i-- {
+		for j := len(bm) - 1; j >= 0; j-- {
+			if am[i] == bm[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	add := func(kind byte, line string) {
+		ops = append(ops, op{kind: kind, line: line, aPos: i, bPos: j})
+	}
+	for ; i < prefix; i, j = i+1, j+1 {
+		add(' ', a[i])
+	}
+	for i < len(a)-suffix || j < len(b)-suffix {
+		switch {
+		case i < len(a)-suffix && j < len(b)-suffix && a[i] == b[j]:
+			add(' ', a[i])
+			i, j = i+1, j+1
+		case i < len(a)-suffix && (j == len(b)-suffix || lcs[i+1-prefix][j-prefix] >= lcs[i-prefix][j+1-prefix]):
+			add('-', a[i])
+			i++
+		default:
+			add('+', b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i, j = i+1, j+1 {
+		add(' ', a[i])
+	}
+	return ops
+}
+
+// hunkEnd returns the end of the hunk whose first change is at start. Changes separated
+// by at most twice the context share a hunk.
+func hunkEnd(ops []op, start int) int {
+	end := start
+	for end < len(ops) {
+		if ops[end].kind != ' ' {
+			end++
+			continue
+		}
+		run := 0
+		for end+run < len(ops) && ops[end+run].kind == ' ' {
+			run++
+		}
+		if end+run == len(ops) || run > 2*contextLines {
+			return end + min(run, contextLines)
+		}
+		end += run
+	}
+	return end
+}
+
+// writeHunk writes a hunk header followed by its lines.
+func writeHunk(out *strings.Builder, hunk []op) {
+	aCount, bCount := 0, 0
+	for _, o := range hunk {
+		if o.kind != '+' {
+			aCount++
+		}
+		if o.kind != '-' {
+			bCount++
+		}
+	}
+	aStart, bStart := hunk[0].aPos, hunk[0].bPos
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, o := range hunk {
+		out.WriteByte(o.kind)
+		out.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/diff"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "Separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n",
+			want: "--- a.tf\n+++ b.tf\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n",
+		},
+		{
+			name: "Created file",
+			a:    "",
+			b:    "x\n",
+			want: "--- a.tf\n+++ b.tf\n@@ -0,0 +1,1 @@\n+x\n",
+		},
+		{
+			name: "Missing final newline",
+			a:    "x\ny",
+			b:    "x\nz\n",
+			want: "--- a.tf\n+++ b.tf\n@@ -1,2 +1,2 @@\n x\n-y\n\\ No newline at end of file\n+z\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := diff.Unified("a.tf", "b.tf", []byte(tc.a), []byte(tc.b))
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected diff (-want +got):\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+// Package docs renders the inputs and outputs of a module as documentation tables.
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Markers delimit the generated section of a README.
+const (
+	BeginMarker = "<!-- BEGIN_TFSORT -->"
+	EndMarker   = "<!-- END_TFSORT -->"
+)
+
+// Format is an output format of Render.
+type Format string
+
+const (
+	// FormatMarkdown renders Markdown tables.
+	FormatMarkdown Format = "markdown"
+	// FormatAsciiDoc renders AsciiDoc tables.
+	FormatAsciiDoc Format = "asciidoc"
+	// FormatJSON renders a JSON document.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a format name.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(value); format {
+	case FormatMarkdown, FormatAsciiDoc, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid format '%s': must be one of markdown, asciidoc, json", value)
+	}
+}
+
+// Input is a variable of a module. Type and Default hold source text.
+type Input struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Default     *string `json:"default"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Sensitive   bool    `json:"sensitive"`
+}
+
+// Output is an output of a module.
+type Output struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Sensitive   bool   `json:"sensitive"`
+}
+
+// Module holds the documented inputs and outputs of a module, in name order.
+type Module struct {
+	Inputs  []Input  `json:"inputs"`
+	Outputs []Output `json:"outputs"`
+}
+
+// Collect gathers the variables and outputs of module. They are ordered by name, as
+// tfsort orders their blocks.
+func Collect(module *hclsort.Module) *Module {
+	doc := &Module{Inputs: []Input{}, Outputs: []Output{}}
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			if len(block.Labels) == 0 {
+				continue
+			}
+			attrs := block.Body.Attributes
+			switch block.Type {
+			case "variable":
+				input := Input{
+					Name:        block.Labels[0],
+					Type:        "any",
+					Description: stringValue(attrs["description"]),
+					Sensitive:   isTrue(attrs["sensitive"]),
+				}
+				if attr, ok := attrs["type"]; ok {
+					input.Type = sourceText(file.Src, attr)
+				}
+				if attr, ok := attrs["default"]; ok {
+					text := sourceText(file.Src, attr)
+					input.Default = &text
+				}
+				input.Required = input.Default == nil
+				doc.Inputs = append(doc.Inputs, input)
+			case "output":
+				doc.Outputs = append(doc.Outputs, Output{
+					Name:        block.Labels[0],
+					Description: stringValue(attrs["description"]),
+					Sensitive:   isTrue(attrs["sensitive"]),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(doc.Inputs, func(a, b int) bool { return doc.Inputs[a].Name < doc.Inputs[b].Name })
+	sort.SliceStable(doc.Outputs, func(a, b int) bool { return doc.Outputs[a].Name < doc.Outputs[b].Name })
+	return doc
+}
+
+// Render renders doc in format.
+func Render(doc *Module, format Format) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(doc), nil
+	case FormatAsciiDoc:
+		return renderAsciiDoc(doc), nil
+	case FormatJSON:
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("error encoding JSON: %w", err)
+		}
+		return string(out) + "\n", nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// Inject replaces the text between BeginMarker and the first EndMarker after it in readme
+// with content. The markers and content are appended when readme has no markers.
+func Inject(readme []byte, content string) ([]byte, error) {
+	section := BeginMarker + "\n" + content + EndMarker
+
+	begin := bytes.Index(readme, []byte(BeginMarker))
+	end := -1
+	if begin >= 0 {
+		if offset := bytes.Index(readme[begin:], []byte(EndMarker)); offset >= 0 {
+			end = begin + offset
+		}
+	}
+	switch {
+	case begin < 0 && !bytes.Contains(readme, []byte(EndMarker)):
+		var buf bytes.Buffer
+		if trimmed := bytes.TrimRight(readme, "\n"); len(trimmed) > 0 {
+			buf.Write(trimmed)
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(section + "\n")
+		return buf.Bytes(), nil
+	case end < 0:
+		return nil, errors.New("the README must contain " + BeginMarker + " followed by " + EndMarker)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(readme[:begin])
+	buf.WriteString(section)
+	buf.Write(readme[end+len(EndMarker):])
+	return buf.Bytes(), nil
+}
+
+// renderMarkdown renders doc as Markdown tables.
+func renderMarkdown(doc *Module) string {
+	var buf strings.Builder
+	buf.WriteString("## Inputs\n\n")
+	if len(doc.Inputs) == 0 {
+		buf.WriteString("No inputs.\n")
+	} else {
+		buf.WriteString("| Name | Type | Default | Description | Required | Sensitive |\n")
+		buf.WriteString("|------|------|---------|-------------|:--------:|:---------:|\n")
+		for _, input := range doc.Inputs {
+			fmt.Fprintf(&buf, "| %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(input.Name), markdownCode(input.Type), markdownDefault(input.Default),
+				markdownCell(input.Description), yesNo(input.Required), yesNo(input.Sensitive))
+		}
+	}
+
+	buf.WriteString("\n## Outputs\n\n")
+	if len(doc.Outputs) == 0 {
+		buf.WriteString("No outputs.\n")
+	} else {
+		buf.WriteString("| Name | Description | Sensitive |\n")
+		buf.WriteString("|------|-------------|:---------:|\n")
+		for _, output := range doc.Outputs {
+			fmt.Fprintf(&buf, "| %s | %s | %s |\n",
+				markdownCell(output.Name), markdownCell(output.Description), yesNo(output.Sensitive))
+		}
+	}
+	return buf.String()
+}
+
+// renderAsciiDoc renders doc as AsciiDoc tables.
+func renderAsciiDoc(doc *Module) string {
+	var buf strings.Builder
+	buf.WriteString("== Inputs\n\n")
+	if len(doc.Inputs) == 0 {
+		buf.WriteString("No inputs.\n")
+	} else {
+		buf.WriteString("[cols=\"2,2,2,4,1,1\",options=\"header\"]\n|===\n")
+		buf.WriteString("|Name |Type |Default |Description |Required |Sensitive\n")
+		for _, input := range doc.Inputs {
+			defaultText := "n/a"
+			if input.Default != nil {
+				defaultText = "`" + asciiDocCell(*input.Default) + "`"
+			}
+			fmt.Fprintf(&buf, "|%s |`%s` |%s |%s |%s |%s\n",
+				asciiDocCell(input.Name), asciiDocCell(input.Type), defaultText,
+				asciiDocCell(input.Description), yesNo(input.Required), yesNo(input.Sensitive))
+		}
+		buf.WriteString("|===\n")
+	}
+
+	buf.WriteString("\n== Outputs\n\n")
+	if len(doc.Outputs) == 0 {
+		buf.WriteString("No outputs.\n")
+	} else {
+		buf.WriteString("[cols=\"2,4,1\",options=\"header\"]\n|===\n")
+		buf.WriteString("|Name |Description |Sensitive\n")
+		for _, output := range doc.Outputs {
+			fmt.Fprintf(&buf, "|%s |%s |%s\n",
+				asciiDocCell(output.Name), asciiDocCell(output.Description), yesNo(output.Sensitive))
+		}
+		buf.WriteString("|===\n")
+	}
+	return buf.String()
+}
+
+// markdownCell puts text on a single line and escapes the table separator.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(singleLine(text), "|", `\|`)
+}
+
+// markdownCode formats text as inline code in a table cell. The code span is fenced by
+// one more backtick than the longest run of backticks in text.
+func markdownCode(text string) string {
+	cell := markdownCell(text)
+	longest, run := 0, 0
+	for _, r := range cell {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	if strings.HasPrefix(cell, "`") || strings.HasSuffix(cell, "`") {
+		// A space keeps a backtick at the edge from extending the fence.
+		cell = " " + cell + " "
+	}
+	fence := strings.Repeat("`", longest+1)
+	return fence + cell + fence
+}
+
+// markdownDefault formats a default value, or "n/a" for required inputs.
+func markdownDefault(text *string) string {
+	if text == nil {
+		return "n/a"
+	}
+	return markdownCode(*text)
+}
+
+// asciiDocCell puts text on a single line and escapes the cell separator.
+func asciiDocCell(text string) string {
+	return strings.ReplaceAll(singleLine(text), "|", `\|`)
+}
+
+// singleLine joins the lines of text with single spaces.
+func singleLine(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, " "))
+}
+
+// yesNo formats a flag for a table.
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// sourceText returns the source text of the value of attr.
+func sourceText(src []byte, attr *hclsyntax.Attribute) string {
+	rng := attr.Expr.Range()
+	return string(src[rng.Start.Byte:rng.End.Byte])
+}
+
+// stringValue returns the value of attr when it is a constant string.
+func stringValue(attr *hclsyntax.Attribute) string {
+	if attr == nil {
+		return ""
+	}
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+		return ""
+	}
+	return value.AsString()
+}
+
+// isTrue reports whether attr is set to the constant true.
+func isTrue(attr *hclsyntax.Attribute) bool {
+	if attr == nil {
+		return false
+	}
+	value, diags := attr.Expr.Value(nil)
+	return !diags.HasErrors() && value.RawEquals(cty.True)
+}
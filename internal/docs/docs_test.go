@@ -0,0 +1,188 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/docs"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+const variablesTF = `variable "region" {
+  description = "AWS region | zone."
+  type        = string
+  default     = "us-east-1"
+}
+
+variable "password" {
+  description = "Database password."
+  type        = string
+  sensitive   = true
+}
+
+variable "tags" {
+  default = {
+    env = "dev"
+  }
+}
+
+output "id" {
+  description = "Instance ID."
+  value       = "x"
+}
+`
+
+func collect(t *testing.T) *docs.Module {
+	t.Helper()
+	file, err := hclsort.NewModuleFile("variables.tf", []byte(variablesTF))
+	if err != nil {
+		t.Fatalf("NewModuleFile failed: %v", err)
+	}
+	return docs.Collect(&hclsort.Module{Files: []*hclsort.ModuleFile{file}})
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format docs.Format
+		want   string
+	}{
+		{
+			format: docs.FormatMarkdown,
+			want: "## Inputs\n\n" +
+				"| Name | Type | Default | Description | Required | Sensitive |\n" +
+				"|------|------|---------|-------------|:--------:|:---------:|\n" +
+				"| password | `string` | n/a | Database password. | yes | yes |\n" +
+				"| region | `string` | `\"us-east-1\"` | AWS region \\| zone. | no | no |\n" +
+				"| tags | `any` | `{ env = \"dev\" }` |  | no | no |\n" +
+				"\n## Outputs\n\n" +
+				"| Name | Description | Sensitive |\n" +
+				"|------|-------------|:---------:|\n" +
+				"| id | Instance ID. | no |\n",
+		},
+		{
+			format: docs.FormatAsciiDoc,
+			want: "== Inputs\n\n[cols=\"2,2,2,4,1,1\",options=\"header\"]\n|===\n" +
+				"|Name |Type |Default |Description |Required |Sensitive\n" +
+				"|password |`string` |n/a |Database password. |yes |yes\n" +
+				"|region |`string` |`\"us-east-1\"` |AWS region \\| zone. |no |no\n" +
+				"|tags |`any` |`{ env = \"dev\" }` | |no |no\n|===\n" +
+				"\n== Outputs\n\n[cols=\"2,4,1\",options=\"header\"]\n|===\n" +
+				"|Name |Description |Sensitive\n|id |Instance ID. |no\n|===\n",
+		},
+	}
+
+	doc := collect(t)
+	for _, tc := range tests {
+		got, err := docs.Render(doc, tc.format)
+		if err != nil {
+			t.Fatalf("Render(%s) failed: %v", tc.format, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected %s output (-want +got):\n%s", tc.format, diff)
+		}
+	}
+}
+
+func TestRenderMarkdownBackticks(t *testing.T) {
+	t.Parallel()
+
+	file, err := hclsort.NewModuleFile("variables.tf", []byte(
+		"variable \"command\" {\n  default = \"run `make`\"\n}\n\n"+
+			"variable \"fence\" {\n  default = \"a ``b`` c\"\n}\n"))
+	if err != nil {
+		t.Fatalf("NewModuleFile failed: %v", err)
+	}
+	got, err := docs.Render(docs.Collect(&hclsort.Module{Files: []*hclsort.ModuleFile{file}}), docs.FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"| command | `any` | ``\"run `make`\"`` |",
+		"| fence | `any` | ```\"a ``b`` c\"``` |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in the output, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	t.Parallel()
+
+	out, err := docs.Render(collect(t), docs.FormatJSON)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	var got docs.Module
+	if err = json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	if diff := cmp.Diff(collect(t), &got); diff != "" {
+		t.Errorf("unexpected JSON document (-want +got):\n%s", diff)
+	}
+}
+
+func TestInject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		readme  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Replaces the section",
+			readme: "# Title\n\n<!-- BEGIN_TFSORT -->\nold\n<!-- END_TFSORT -->\n\nFooter.\n",
+			want:   "# Title\n\n<!-- BEGIN_TFSORT -->\nnew\n<!-- END_TFSORT -->\n\nFooter.\n",
+		},
+		{
+			name:   "Appends missing markers",
+			readme: "# Title\n",
+			want:   "# Title\n\n<!-- BEGIN_TFSORT -->\nnew\n<!-- END_TFSORT -->\n",
+		},
+		{
+			name: "Ignores an end marker before the section",
+			readme: "Close the section with `<!-- END_TFSORT -->`.\n\n" +
+				"<!-- BEGIN_TFSORT -->\nold\n<!-- END_TFSORT -->\n",
+			want: "Close the section with `<!-- END_TFSORT -->`.\n\n" +
+				"<!-- BEGIN_TFSORT -->\nnew\n<!-- END_TFSORT -->\n",
+		},
+		{
+			name:    "Rejects a stray end marker",
+			readme:  "<!-- END_TFSORT -->\n",
+			wantErr: true,
+		},
+		{
+			name:    "Rejects an unterminated section",
+			readme:  "<!-- BEGIN_TFSORT -->\nold\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := docs.Inject([]byte(tc.readme), "new\n")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Inject failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("unexpected README (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
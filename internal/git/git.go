@@ -0,0 +1,216 @@
+// Package git provides helpers for querying a local git repository and updating its
+// index. It shells out to the git binary and never touches the network.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// rawDiffFields is the number of metadata fields of an entry in "git diff --raw" output.
+const rawDiffFields = 5
+
+// maxConflictStatus bounds the exit status of "git merge-file" that counts conflicts;
+// higher values are errors.
+const maxConflictStatus = 128
+
+// TopLevel returns the absolute path of the working tree root containing dir.
+func TopLevel(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(out))), nil
+}
+
+// ChangedFiles returns the files that were added, copied, modified or renamed in the
+// working tree relative to ref, including untracked files that are not ignored.
+// The returned paths are absolute.
+func ChangedFiles(dir, ref string) ([]string, error) {
+	if ref == "" {
+		return nil, errors.New("git ref is required")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref '%s'", ref)
+	}
+
+	root, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := run(root, "diff", "--name-only", "-z", "--diff-filter=ACMR", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := run(root, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	return joinPaths(root, changed, untracked), nil
+}
+
+// StagedFiles returns the files that were added, copied, modified or renamed in the index
+// relative to HEAD. The returned paths are absolute.
+func StagedFiles(dir string) ([]string, error) {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := run(root, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR", "--")
+	if err != nil {
+		return nil, err
+	}
+
+	return joinPaths(root, staged), nil
+}
+
+// IndexEntry is a file staged in the index.
+type IndexEntry struct {
+	// Path is relative to the working tree root and uses forward slashes.
+	Path string
+	// Mode is the octal file mode, e.g. "100644".
+	Mode string
+	// Hash is the object name of the staged content.
+	Hash string
+}
+
+// HooksDir returns the absolute path of the hooks directory of the repository containing
+// dir, honoring core.hooksPath.
+func HooksDir(dir string) (string, error) {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := run(root, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+
+	hooks := filepath.FromSlash(strings.TrimSpace(string(out)))
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(root, hooks)
+	}
+	return hooks, nil
+}
+
+// StagedEntries returns the index entries of the files that were added, copied or
+// modified in the index relative to HEAD. Renames are reported as additions.
+func StagedEntries(dir string) ([]IndexEntry, error) {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := run(root, "diff", "--cached", "--raw", "-z", "--no-abbrev", "--no-renames",
+		"--diff-filter=ACM", "--")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each entry is ":<old mode> <new mode> <old hash> <new hash> <status>" followed by the path.
+	fields := strings.Split(string(out), "\x00")
+	entries := []IndexEntry{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		meta := strings.Fields(strings.TrimPrefix(fields[i], ":"))
+		if len(meta) != rawDiffFields {
+			return nil, fmt.Errorf("unexpected git diff output '%s'", fields[i])
+		}
+		entries = append(entries, IndexEntry{Path: fields[i+1], Mode: meta[1], Hash: meta[3]})
+	}
+
+	return entries, nil
+}
+
+// ReadBlob returns the content of the blob with the given hash.
+func ReadBlob(dir, hash string) ([]byte, error) {
+	return run(dir, "cat-file", "blob", hash)
+}
+
+// WriteBlob stores data in the object database as is, without applying any filters,
+// and returns its hash.
+func WriteBlob(dir string, data []byte) (string, error) {
+	out, err := runInput(dir, data, "hash-object", "-w", "--no-filters", "--stdin")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// UpdateIndex stages entry without touching the working tree.
+func UpdateIndex(dir string, entry IndexEntry) error {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return err
+	}
+
+	_, err = run(root, "update-index", "--cacheinfo", entry.Mode+","+entry.Hash+","+entry.Path)
+	return err
+}
+
+// MergeFile runs a line-based three-way merge of base and other into current, as git does
+// by default, and reports whether conflict markers were written.
+func MergeFile(current, base, other string) (bool, error) {
+	_, err := run(".", "merge-file", "-L", "ours", "-L", "base", "-L", "theirs", "--", current, base, other)
+
+	// merge-file exits with the number of conflicts, or a negative status on failure.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < maxConflictStatus {
+		return true, nil
+	}
+	return false, err
+}
+
+// run executes git with the given arguments inside dir and returns its standard output.
+func run(dir string, args ...string) ([]byte, error) {
+	return runInput(dir, nil, args...)
+}
+
+// runInput is run with input fed to git's standard input.
+func runInput(dir string, input []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return nil, fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+		return nil, fmt.Errorf("git %s failed: %s: %w", args[0], msg, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// joinPaths splits NUL-separated git output and resolves every entry against root.
+func joinPaths(root string, outputs ...[]byte) []string {
+	seen := map[string]bool{}
+	paths := []string{}
+
+	for _, out := range outputs {
+		for _, name := range strings.Split(string(out), "\x00") {
+			if name == "" {
+				continue
+			}
+			path := filepath.Join(root, filepath.FromSlash(name))
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,187 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/git"
+	"github.com/google/go-cmp/cmp"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeFile(t, filepath.Join(dir, "main.tf"), `variable "a" {}`)
+	writeFile(t, filepath.Join(dir, "outputs.tf"), `output "a" {}`)
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+
+	root, err := git.TopLevel(dir)
+	if err != nil {
+		t.Fatalf("TopLevel failed: %v", err)
+	}
+	return root
+}
+
+func TestChangedFiles(t *testing.T) {
+	root := setupRepo(t)
+
+	writeFile(t, filepath.Join(root, "main.tf"), `variable "b" {}`)
+	writeFile(t, filepath.Join(root, "modules", "x", "new.tf"), `variable "c" {}`)
+
+	got, err := git.ChangedFiles(root, "HEAD")
+	if err != nil {
+		t.Fatalf("ChangedFiles failed: %v", err)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		filepath.Join(root, "main.tf"),
+		filepath.Join(root, "modules", "x", "new.tf"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected changed files (-want +got):\n%s", diff)
+	}
+
+	t.Run("Rejects option-like ref", func(t *testing.T) {
+		if _, errRef := git.ChangedFiles(root, "--output=/tmp/x"); errRef == nil {
+			t.Error("Expected error for ref starting with '-' but got nil")
+		}
+	})
+
+	t.Run("Unknown ref", func(t *testing.T) {
+		if _, errRef := git.ChangedFiles(root, "does-not-exist"); errRef == nil {
+			t.Error("Expected error for unknown ref but got nil")
+		}
+	})
+}
+
+func TestStagedFiles(t *testing.T) {
+	root := setupRepo(t)
+
+	writeFile(t, filepath.Join(root, "main.tf"), `variable "b" {}`)
+	writeFile(t, filepath.Join(root, "outputs.tf"), `output "b" {}`)
+	runGit(t, root, "add", "main.tf")
+
+	got, err := git.StagedFiles(root)
+	if err != nil {
+		t.Fatalf("StagedFiles failed: %v", err)
+	}
+
+	want := []string{filepath.Join(root, "main.tf")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected staged files (-want +got):\n%s", diff)
+	}
+}
+
+func TestStagedEntriesAndUpdateIndex(t *testing.T) {
+	root := setupRepo(t)
+
+	writeFile(t, filepath.Join(root, "main.tf"), `variable "b" {}`)
+	runGit(t, root, "add", "main.tf")
+
+	entries, err := git.StagedEntries(root)
+	if err != nil {
+		t.Fatalf("StagedEntries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "main.tf" || entries[0].Mode != "100644" {
+		t.Fatalf("unexpected staged entries: %+v", entries)
+	}
+
+	content, err := git.ReadBlob(root, entries[0].Hash)
+	if err != nil {
+		t.Fatalf("ReadBlob failed: %v", err)
+	}
+	if string(content) != `variable "b" {}` {
+		t.Errorf("unexpected blob content: %q", content)
+	}
+
+	entry := entries[0]
+	if entry.Hash, err = git.WriteBlob(root, []byte(`variable "c" {}`)); err != nil {
+		t.Fatalf("WriteBlob failed: %v", err)
+	}
+	if err = git.UpdateIndex(root, entry); err != nil {
+		t.Fatalf("UpdateIndex failed: %v", err)
+	}
+
+	entries, err = git.StagedEntries(root)
+	if err != nil {
+		t.Fatalf("StagedEntries failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Hash != entry.Hash {
+		t.Errorf("expected the new blob to be staged, got %+v", entries)
+	}
+}
+
+func TestHooksDir(t *testing.T) {
+	root := setupRepo(t)
+
+	got, err := git.HooksDir(root)
+	if err != nil {
+		t.Fatalf("HooksDir failed: %v", err)
+	}
+	if want := filepath.Join(root, ".git", "hooks"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	runGit(t, root, "config", "core.hooksPath", "custom-hooks")
+	if got, err = git.HooksDir(root); err != nil || got != filepath.Join(root, "custom-hooks") {
+		t.Errorf("expected core.hooksPath to be honored, got %s (%v)", got, err)
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base")
+	current := filepath.Join(dir, "current")
+	other := filepath.Join(dir, "other")
+
+	writeFile(t, base, "a\nb\nc\n")
+	writeFile(t, current, "a\nb\nc\nd\n")
+	writeFile(t, other, "z\nb\nc\n")
+	conflicted, err := git.MergeFile(current, base, other)
+	if err != nil || conflicted {
+		t.Fatalf("expected a clean merge, got conflicted=%v err=%v", conflicted, err)
+	}
+	if got, _ := os.ReadFile(current); string(got) != "z\nb\nc\nd\n" {
+		t.Errorf("unexpected merge result: %q", got)
+	}
+
+	writeFile(t, other, "z\nb\nc\ne\n")
+	if conflicted, err = git.MergeFile(current, base, other); err != nil || !conflicted {
+		t.Errorf("expected a conflict, got conflicted=%v err=%v", conflicted, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package githook installs the tfsort git pre-commit hook and implements the work the
+// hook does: sorting the staged content of Terraform files.
+package githook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlexNabokikh/tfsort/internal/git"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+)
+
+const (
+	// HookName is the git hook that tfsort installs.
+	HookName = "pre-commit"
+	// chainedSuffix is appended to the name of a hook that existed before tfsort's.
+	chainedSuffix = ".pre-tfsort"
+	// marker identifies hooks written by tfsort.
+	marker = "# Installed by tfsort install-hook."
+	// hookMode makes the hook executable, as git requires.
+	hookMode = 0755
+)
+
+// Install writes the pre-commit hook into hooksDir. The hook runs command and, if it
+// succeeds, the pre-commit hook that was there before, which is kept under a different
+// name. Installing again only updates the hook.
+func Install(hooksDir, command string) error {
+	if err := os.MkdirAll(hooksDir, hookMode); err != nil {
+		return fmt.Errorf("failed to create hooks directory '%s': %w", hooksDir, err)
+	}
+
+	hook := filepath.Join(hooksDir, HookName)
+	chained := hook + chainedSuffix
+
+	existing, err := os.ReadFile(hook)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// There is no hook to chain to.
+	case err != nil:
+		return fmt.Errorf("failed to read hook '%s': %w", hook, err)
+	case !bytes.Contains(existing, []byte(marker)):
+		if _, statErr := os.Lstat(chained); statErr == nil {
+			return fmt.Errorf("cannot keep the existing hook: '%s' already exists", chained)
+		}
+		if err = os.Rename(hook, chained); err != nil {
+			return fmt.Errorf("failed to move the existing hook aside: %w", err)
+		}
+	}
+
+	//nolint:gosec // git only runs hooks that are executable
+	if err = os.WriteFile(hook, []byte(script(command)), hookMode); err != nil {
+		return fmt.Errorf("failed to write hook '%s': %w", hook, err)
+	}
+	if err = os.Chmod(hook, hookMode); err != nil {
+		return fmt.Errorf("failed to make hook '%s' executable: %w", hook, err)
+	}
+	return nil
+}
+
+// Uninstall removes the hook written by Install and puts back the hook it chained to.
+func Uninstall(hooksDir string) error {
+	hook := filepath.Join(hooksDir, HookName)
+	chained := hook + chainedSuffix
+
+	existing, err := os.ReadFile(hook)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no %s hook is installed in '%s'", HookName, hooksDir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read hook '%s': %w", hook, err)
+	}
+	if !bytes.Contains(existing, []byte(marker)) {
+		return fmt.Errorf("the %s hook in '%s' was not installed by tfsort", HookName, hooksDir)
+	}
+
+	if err = os.Remove(hook); err != nil {
+		return fmt.Errorf("failed to remove hook '%s': %w", hook, err)
+	}
+	if _, err = os.Lstat(chained); err == nil {
+		if err = os.Rename(chained, hook); err != nil {
+			return fmt.Errorf("failed to restore the previous hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// script returns the hook that runs command and then the chained hook.
+func script(command string) string {
+	return `#!/bin/sh
+` + marker + `
+# Sorts the staged Terraform files, then runs the hook that was installed before, if any.
+` + command + ` || exit $?
+chained="$(dirname "$0")/` + HookName + chainedSuffix + `"
+if [ -x "$chained" ]; then
+	exec "$chained" "$@"
+fi
+`
+}
+
+// ShellQuote quotes s for use as a single word in a POSIX shell command.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SortStaged sorts the staged content of every added or modified file that the ingestor
+// accepts and does not skip and that filter, if not nil, keeps, and stages the result. The filter sees the paths
+// as if the working tree root was walked. It works on the index rather than the working
+// tree, so unstaged changes of partially staged files are never committed. A working tree
+// file is rewritten as well when it matches the index. It returns the re-staged paths,
+// relative to the working tree root.
+func SortStaged(dir string, ingestor *hclsort.Ingestor, filter *hclsort.PathFilter) ([]string, error) {
+	root, err := git.TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := git.StagedEntries(root)
+	if err != nil {
+		return nil, err
+	}
+
+	restaged := []string{}
+	for _, entry := range entries {
+		// Symbolic links and submodules have no content to sort.
+		if entry.Mode != "100644" && entry.Mode != "100755" {
+			continue
+		}
+		if !ingestor.AllowedTypes[strings.TrimPrefix(filepath.Ext(entry.Path), ".")] {
+			continue
+		}
+		path := filepath.Join(root, filepath.FromSlash(entry.Path))
+		if filter != nil {
+			if skip, _ := filter.Skip(root, path, false); skip {
+				continue
+			}
+		}
+		if skip, reason := ingestor.SkipFile(path); skip {
+			if ingestor.Logger != nil {
+				ingestor.Logger.Printf("Warning: skipping %s: %s", entry.Path, reason)
+			}
+			continue
+		}
+
+		changed, sortErr := sortEntry(root, ingestor, entry)
+		if sortErr != nil {
+			return restaged, fmt.Errorf("error sorting staged file '%s': %w", entry.Path, sortErr)
+		}
+		if changed {
+			restaged = append(restaged, entry.Path)
+		}
+	}
+
+	return restaged, nil
+}
+
+// sortEntry sorts and re-stages a single index entry. It reports whether it changed.
+func sortEntry(root string, ingestor *hclsort.Ingestor, entry git.IndexEntry) (bool, error) {
+	staged, err := git.ReadBlob(root, entry.Hash)
+	if err != nil {
+		return false, err
+	}
+
+	sorted, err := ingestor.Sort(staged, entry.Path)
+	if err != nil || bytes.Equal(sorted, staged) {
+		return false, err
+	}
+
+	if entry.Hash, err = git.WriteBlob(root, sorted); err != nil {
+		return false, err
+	}
+	if err = git.UpdateIndex(root, entry); err != nil {
+		return false, err
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(entry.Path))
+	if current, readErr := os.ReadFile(path); readErr == nil && bytes.Equal(current, staged) {
+		if err = (hclsort.OSFileSystem{}).WriteFile(path, sorted); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,160 @@
+package githook_test
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/githook"
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	sorted   = "variable \"a\" {}\n\nvariable \"b\" {}\n"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestInstall(t *testing.T) {
+	t.Parallel()
+
+	hooksDir := filepath.Join(t.TempDir(), "hooks")
+	hook := filepath.Join(hooksDir, githook.HookName)
+	const previous = "#!/bin/sh\necho previous\n"
+
+	writeFile(t, hook, previous)
+
+	for range 2 {
+		if err := githook.Install(hooksDir, "tfsort run-hook"); err != nil {
+			t.Fatalf("Install failed: %v", err)
+		}
+	}
+
+	if got := readFile(t, hook+".pre-tfsort"); got != previous {
+		t.Errorf("expected the previous hook to be kept, got:\n%s", got)
+	}
+	script := readFile(t, hook)
+	if !strings.Contains(script, "tfsort run-hook || exit $?") || !strings.Contains(script, "pre-commit.pre-tfsort") {
+		t.Errorf("expected the hook to run tfsort and chain, got:\n%s", script)
+	}
+	if info, err := os.Stat(hook); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected the hook to be executable, got %v (%v)", info.Mode(), err)
+	}
+
+	if err := githook.Uninstall(hooksDir); err != nil {
+		t.Fatalf("Uninstall failed: %v", err)
+	}
+	if got := readFile(t, hook); got != previous {
+		t.Errorf("expected the previous hook to be restored, got:\n%s", got)
+	}
+	if _, err := os.Stat(hook + ".pre-tfsort"); !os.IsNotExist(err) {
+		t.Errorf("expected the chained copy to be gone, got %v", err)
+	}
+
+	if err := githook.Uninstall(hooksDir); err == nil {
+		t.Error("expected an error when uninstalling a hook not written by tfsort")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	if got := githook.ShellQuote("/opt/it's here/tfsort"); got != `'/opt/it'\''s here/tfsort'` {
+		t.Errorf("unexpected quoting: %s", got)
+	}
+}
+
+func TestSortStaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	root := t.TempDir()
+	runGit(t, root, "init", "-q")
+	writeFile(t, filepath.Join(root, "README.md"), "readme\n")
+	runGit(t, root, "add", ".")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+
+	// full.tf is fully staged; partial.tf has an unstaged edit on top of the staged content.
+	writeFile(t, filepath.Join(root, "full.tf"), unsorted)
+	writeFile(t, filepath.Join(root, "partial.tf"), unsorted)
+	writeFile(t, filepath.Join(root, "notes.txt"), unsorted)
+	writeFile(t, filepath.Join(root, "examples", "main.tf"), unsorted)
+	writeFile(t, filepath.Join(root, "generated.tf"), unsorted)
+	writeFile(t, filepath.Join(root, hclsort.IgnoreFileName), "generated.tf\n")
+	writeFile(t, filepath.Join(root, "legacy.tf"), unsorted)
+	writeFile(t, filepath.Join(root, "legacy.tofu"), sorted)
+	writeFile(t, filepath.Join(root, "main_override.tf"), unsorted)
+	runGit(t, root, "add", ".")
+	const unstaged = unsorted + "variable \"c\" {}\n"
+	writeFile(t, filepath.Join(root, "partial.tf"), unstaged)
+
+	filter, err := hclsort.NewPathFilter(nil, []string{"examples/**"}, false)
+	if err != nil {
+		t.Fatalf("NewPathFilter failed: %v", err)
+	}
+	ingestor := hclsort.NewIngestor()
+	ingestor.Logger = log.New(io.Discard, "", 0)
+	restaged, err := githook.SortStaged(root, ingestor, filter)
+	if err != nil {
+		t.Fatalf("SortStaged failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"full.tf", "partial.tf"}, restaged); diff != "" {
+		t.Errorf("unexpected re-staged files (-want +got):\n%s", diff)
+	}
+
+	for _, name := range []string{"full.tf", "partial.tf"} {
+		if got := runGit(t, root, "show", ":"+name); got != sorted {
+			t.Errorf("expected staged %s to be sorted, got:\n%s", name, got)
+		}
+	}
+	for _, name := range []string{"notes.txt", "examples/main.tf", "generated.tf", "legacy.tf", "main_override.tf"} {
+		if got := runGit(t, root, "show", ":"+name); got != unsorted {
+			t.Errorf("expected %s to be left alone, got:\n%s", name, got)
+		}
+	}
+
+	if got := readFile(t, filepath.Join(root, "full.tf")); got != sorted {
+		t.Errorf("expected full.tf in the working tree to be sorted, got:\n%s", got)
+	}
+	if got := readFile(t, filepath.Join(root, "partial.tf")); got != unstaged {
+		t.Errorf("expected unstaged changes of partial.tf to be kept, got:\n%s", got)
+	}
+}
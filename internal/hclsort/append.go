@@ -0,0 +1,34 @@
+package hclsort
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+)
+
+// AppendBlocks plans appending text, which must use LF line endings, to the file at path
+// and sorting the result, so the new blocks end up in their sorted position. The file is
+// created if it does not exist.
+func (i *Ingestor) AppendBlocks(path string, text []byte) (FileChange, error) {
+	src, err := i.fileSystem().ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return FileChange{}, err
+	}
+	change := FileChange{Path: path}
+	if err == nil {
+		change.Before = src
+	}
+
+	style := DetectTextStyle(src)
+	content := trimTrailingBlankLines(style.Normalize(src))
+	if len(content) > 0 {
+		content = append(content, '\n')
+	}
+	content = append(content, bytes.TrimLeft(text, "\n")...)
+
+	change.After, err = i.sortWithStyle(content, path, style.WithLineEndings(i.LineEndings))
+	if err != nil {
+		return FileChange{}, err
+	}
+	return change, nil
+}
@@ -0,0 +1,36 @@
+package hclsort_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendBlocks(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"variables.tf": "variable \"a\" {}\r\n\r\nvariable \"c\" {}\r\n",
+	})
+	stub := []byte("variable \"b\" {\n  type = any\n}\n\n")
+
+	change, err := ingestor.AppendBlocks("variables.tf", stub)
+	if err != nil {
+		t.Fatalf("AppendBlocks failed: %v", err)
+	}
+	want := "variable \"a\" {}\r\n\r\nvariable \"b\" {\r\n  type = any\r\n}\r\n\r\nvariable \"c\" {}\r\n"
+	if diff := cmp.Diff(want, string(change.After)); diff != "" {
+		t.Errorf("unexpected content (-want +got):\n%s", diff)
+	}
+
+	change, err = ingestor.AppendBlocks("new.tf", stub)
+	if err != nil {
+		t.Fatalf("AppendBlocks failed: %v", err)
+	}
+	if change.Before != nil {
+		t.Errorf("expected a new file, got %q", change.Before)
+	}
+	if diff := cmp.Diff("variable \"b\" {\n  type = any\n}\n", string(change.After)); diff != "" {
+		t.Errorf("unexpected content (-want +got):\n%s", diff)
+	}
+}
@@ -2,8 +2,11 @@ package hclsort
 
 import (
 	"bytes"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -46,17 +49,51 @@ func CheckFileExtension(path string, allowedTypes map[string]bool) error {
 	return nil
 }
 
+// ParseSymlinkPolicy converts a flag value into a SymlinkPolicy.
+func ParseSymlinkPolicy(value string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(value); policy {
+	case SymlinkFollow, SymlinkSkip, SymlinkError:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid symlink policy '%s': must be one of follow, skip, error", value)
+	}
+}
+
+// CheckSymlink applies policy to path. It reports whether the path should be skipped,
+// or returns an error when the policy forbids rewriting a symbolic link.
+func CheckSymlink(fsys FileSystem, path string, policy SymlinkPolicy) (bool, error) {
+	info, err := fsys.Lstat(path)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false, nil
+	}
+
+	switch policy {
+	case SymlinkSkip:
+		return true, nil
+	case SymlinkError:
+		return false, fmt.Errorf("refusing to rewrite symlink '%s'", path)
+	case SymlinkFollow:
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
 // ReadFileBytes reads the content of the file at the given path.
-func ReadFileBytes(path string) ([]byte, error) {
-	src, err := os.ReadFile(path)
+func ReadFileBytes(fsys FileSystem, path string) ([]byte, error) {
+	src, err := fsys.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file '%s': %w", path, err)
 	}
 	return src, nil
 }
 
-// WriteSortedContent handles writing the outputBytes to the specified destination.
+// WriteSortedContent handles writing the outputBytes to the specified destination:
+// a file in fsys, or stdout for dry runs and stdin input.
+// Surrounding whitespace is trimmed and a single trailing newline is added.
 func WriteSortedContent(
+	fsys FileSystem,
+	stdout io.Writer,
 	originalPathOrMarker string,
 	outputPath string,
 	dryRun bool,
@@ -64,10 +101,24 @@ func WriteSortedContent(
 	isInputFromStdin bool,
 ) error {
 	finalBytes := append(bytes.TrimSpace(outputBytes), '\n')
+	return writeContent(fsys, stdout, originalPathOrMarker, outputPath, dryRun, finalBytes, isInputFromStdin)
+}
 
+// writeContent writes finalBytes verbatim to the destination chosen like
+// WriteSortedContent does. Parse uses it because TextStyle.Restore already produced
+// the final layout, including line endings and the final newline.
+func writeContent(
+	fsys FileSystem,
+	stdout io.Writer,
+	originalPathOrMarker string,
+	outputPath string,
+	dryRun bool,
+	finalBytes []byte,
+	isInputFromStdin bool,
+) error {
 	switch {
 	case outputPath != "":
-		err := os.WriteFile(outputPath, finalBytes, 0644)
+		err := fsys.WriteFile(outputPath, finalBytes)
 		if err != nil {
 			return fmt.Errorf(
 				"error writing output to file '%s': %w",
@@ -76,14 +127,14 @@ func WriteSortedContent(
 			)
 		}
 	case dryRun:
-		fmt.Print(string(finalBytes))
+		_, _ = stdout.Write(finalBytes)
 	case isInputFromStdin:
-		_, err := fmt.Print(string(finalBytes))
+		_, err := stdout.Write(finalBytes)
 		if err != nil {
 			return fmt.Errorf("error writing to stdout: %w", err)
 		}
 	default:
-		err := os.WriteFile(originalPathOrMarker, finalBytes, 0644)
+		err := fsys.WriteFile(originalPathOrMarker, finalBytes)
 		if err != nil {
 			return fmt.Errorf(
 				"error writing output to file '%s': %w",
@@ -94,3 +145,89 @@ func WriteSortedContent(
 	}
 	return nil
 }
+
+const (
+	// tempAttempts is how many names createTemp tries before giving up.
+	tempAttempts = 10
+	// newFileMode is the mode of a created file before the umask applies, as with os.WriteFile.
+	newFileMode = 0666
+	// tempFileMode keeps the copy of an existing file private until its mode is restored.
+	tempFileMode = 0600
+	// writeBits are the permission bits that allow writing to a file.
+	writeBits = 0222
+)
+
+// writeFileAtomic replaces the file at path with data without ever leaving it truncated.
+// The content is written to a temporary file in the same directory, synced and renamed
+// into place. Symbolic links are resolved so the link itself survives, and the mode and
+// ownership of an existing file are preserved. New files are created subject to the umask,
+// like os.WriteFile with mode 0666.
+func writeFileAtomic(path string, data []byte) error {
+	target := path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		target = resolved
+	}
+
+	info, err := os.Stat(target)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		info = nil
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("'%s' is not a regular file", target)
+	case info.Mode().Perm()&writeBits == 0:
+		// Renaming would succeed in a writable directory, so read-only files are refused here.
+		return fmt.Errorf("'%s' is read-only", target)
+	}
+
+	perm := os.FileMode(newFileMode)
+	if info != nil {
+		perm = tempFileMode
+	}
+	tmp, err := createTemp(target, perm)
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if info != nil {
+		if err = os.Chmod(tmpName, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		preserveOwner(tmpName, info)
+	}
+
+	if err = os.Rename(tmpName, target); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(target))
+
+	return nil
+}
+
+// createTemp creates a file with a random name next to target. Unlike os.CreateTemp, it
+// opens the file with perm, so the umask applies.
+func createTemp(target string, perm os.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".tfsort-")
+	for range tempAttempts {
+		f, err := os.OpenFile(prefix+rand.Text(), os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("failed to create a temporary file next to '%s'", target)
+}
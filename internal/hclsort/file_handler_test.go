@@ -0,0 +1,185 @@
+package hclsort_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePreservesFileMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "variables.tf")
+	if err := os.WriteFile(path, []byte("variable \"b\" {}\nvariable \"a\" {}\n"), 0600); err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatalf("Failed to set file mode: %v", err)
+	}
+
+	if err := hclsort.NewIngestor().Parse(path, "", false, false); err != nil {
+		t.Fatalf("Parse failed unexpectedly: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat rewritten file: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode 0640 to be preserved, got %o", info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}
+
+func TestParseNewFileHonorsUmask(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "variables.tf")
+	if err := os.WriteFile(input, []byte("variable \"b\" {}\nvariable \"a\" {}\n"), 0600); err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	// os.WriteFile applies the umask to 0666, which is what a created output file should get.
+	reference := filepath.Join(dir, "reference")
+	if err := os.WriteFile(reference, nil, 0666); err != nil {
+		t.Fatalf("Failed to create reference file: %v", err)
+	}
+	want, err := os.Stat(reference)
+	if err != nil {
+		t.Fatalf("Failed to stat reference file: %v", err)
+	}
+
+	output := filepath.Join(dir, "sorted.tf")
+	if err = hclsort.NewIngestor().Parse(input, output, false, false); err != nil {
+		t.Fatalf("Parse failed unexpectedly: %v", err)
+	}
+
+	got, err := os.Stat(output)
+	if err != nil {
+		t.Fatalf("Failed to stat output file: %v", err)
+	}
+	if got.Mode().Perm() != want.Mode().Perm() {
+		t.Errorf("expected mode %o, got %o", want.Mode().Perm(), got.Mode().Perm())
+	}
+}
+
+func TestWriteSortedContent(t *testing.T) {
+	t.Parallel()
+
+	fsys := hclsort.NewMemFileSystem(nil)
+	var stdout bytes.Buffer
+	content := []byte("\nvariable \"a\" {}\r\n\n\n")
+
+	if err := hclsort.WriteSortedContent(fsys, &stdout, "in.tf", "out.tf", false, content, false); err != nil {
+		t.Fatalf("WriteSortedContent failed: %v", err)
+	}
+	got, err := fsys.ReadFile("out.tf")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("variable \"a\" {}\n", string(got)); diff != "" {
+		t.Errorf("unexpected file content (-want +got):\n%s", diff)
+	}
+
+	if err = hclsort.WriteSortedContent(fsys, &stdout, "in.tf", "", true, content, false); err != nil {
+		t.Fatalf("WriteSortedContent failed: %v", err)
+	}
+	if diff := cmp.Diff("variable \"a\" {}\n", stdout.String()); diff != "" {
+		t.Errorf("unexpected dry-run output (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseSymlinks(t *testing.T) {
+	const unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	const sorted = "variable \"a\" {}\n\nvariable \"b\" {}\n"
+
+	setup := func(t *testing.T) (string, string) {
+		t.Helper()
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target.tf")
+		link := filepath.Join(dir, "link.tf")
+		if err := os.WriteFile(target, []byte(unsorted), 0600); err != nil {
+			t.Fatalf("Failed to create target file: %v", err)
+		}
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Symlinks are not supported: %v", err)
+		}
+		return target, link
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		return string(content)
+	}
+
+	newIngestor := func(policy hclsort.SymlinkPolicy) (*hclsort.Ingestor, *strings.Builder) {
+		var logs strings.Builder
+		ingestor := hclsort.NewIngestor()
+		ingestor.SymlinkPolicy = policy
+		ingestor.Logger = log.New(&logs, "", 0)
+		return ingestor, &logs
+	}
+
+	t.Run("Follow", func(t *testing.T) {
+		target, link := setup(t)
+		ingestor, _ := newIngestor(hclsort.SymlinkFollow)
+
+		if err := ingestor.Parse(link, "", false, false); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+		if got := readFile(t, target); got != sorted {
+			t.Errorf("expected target to be sorted, got:\n%s", got)
+		}
+		if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("expected %s to still be a symlink", link)
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		target, link := setup(t)
+		ingestor, logs := newIngestor(hclsort.SymlinkSkip)
+
+		if err := ingestor.Parse(link, "", false, false); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+		if got := readFile(t, target); got != unsorted {
+			t.Errorf("expected target to be untouched, got:\n%s", got)
+		}
+		if !strings.Contains(logs.String(), "Skipping symlink "+link) {
+			t.Errorf("expected the skipped symlink to be reported, got %q", logs.String())
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		target, link := setup(t)
+		ingestor, _ := newIngestor(hclsort.SymlinkError)
+
+		err := ingestor.Parse(link, "", false, false)
+		if err == nil || !strings.Contains(err.Error(), "refusing to rewrite symlink") {
+			t.Errorf("expected symlink error, got: %v", err)
+		}
+		if got := readFile(t, target); got != unsorted {
+			t.Errorf("expected target to be untouched, got:\n%s", got)
+		}
+	})
+
+	t.Run("Invalid policy", func(t *testing.T) {
+		if _, err := hclsort.ParseSymlinkPolicy("ignore"); err == nil {
+			t.Error("Expected error for invalid symlink policy but got nil")
+		}
+	})
+}
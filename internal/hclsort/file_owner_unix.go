@@ -0,0 +1,26 @@
+//go:build !windows
+
+package hclsort
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner copies the owner and group of info to path on a best-effort basis.
+// Changing ownership usually requires privileges, so failures are ignored.
+func preserveOwner(path string, info os.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Lchown(path, int(stat.Uid), int(stat.Gid))
+	}
+}
+
+// syncDir flushes directory metadata so a completed rename survives a crash.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package hclsort
+
+import "os"
+
+// preserveOwner is a no-op on Windows, where files have no POSIX owner.
+func preserveOwner(_ string, _ os.FileInfo) {}
+
+// syncDir is a no-op on Windows, where directories cannot be synced.
+func syncDir(_ string) {}
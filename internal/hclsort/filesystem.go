@@ -0,0 +1,145 @@
+package hclsort
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileSystem abstracts the file operations performed by the Ingestor. Names are
+// operating system paths, as accepted by the os package.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	// WriteFile replaces the content of name, preserving the mode of an existing file.
+	WriteFile(name string, data []byte) error
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// OSFileSystem is the FileSystem backed by the operating system. Writes are atomic.
+type OSFileSystem struct{}
+
+// MemFileSystem is an in-memory FileSystem that is safe for concurrent use.
+// It has no directories and no symbolic links.
+type MemFileSystem struct {
+	mu    sync.RWMutex
+	files map[string]*memFile
+}
+
+// memFile is a single file held by MemFileSystem.
+type memFile struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// ReadFile implements FileSystem.
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile implements FileSystem.
+func (OSFileSystem) WriteFile(name string, data []byte) error {
+	return writeFileAtomic(name, data)
+}
+
+// Stat implements FileSystem.
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat implements FileSystem.
+func (OSFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// NewMemFileSystem returns a MemFileSystem holding the given files.
+func NewMemFileSystem(files map[string]string) *MemFileSystem {
+	m := &MemFileSystem{files: make(map[string]*memFile, len(files))}
+	for name, content := range files {
+		m.files[filepath.Clean(name)] = &memFile{
+			name:    filepath.Base(name),
+			data:    []byte(content),
+			mode:    0644,
+			modTime: time.Now(),
+		}
+	}
+	return m
+}
+
+// ReadFile implements FileSystem.
+func (m *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte{}, f.data...), nil
+}
+
+// WriteFile implements FileSystem.
+func (m *MemFileSystem) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := filepath.Clean(name)
+	mode := fs.FileMode(0644)
+	if f, ok := m.files[key]; ok {
+		mode = f.mode
+	}
+	m.files[key] = &memFile{
+		name:    filepath.Base(name),
+		data:    append([]byte{}, data...),
+		mode:    mode,
+		modTime: time.Now(),
+	}
+	return nil
+}
+
+// Stat implements FileSystem.
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFileInfo{name: f.name, size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}, nil
+}
+
+// Lstat implements FileSystem. MemFileSystem has no symbolic links, so it equals Stat.
+func (m *MemFileSystem) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(name)
+}
+
+// memFileInfo is the fs.FileInfo of a memFile snapshot.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// Name implements fs.FileInfo.
+func (i *memFileInfo) Name() string { return i.name }
+
+// Size implements fs.FileInfo.
+func (i *memFileInfo) Size() int64 { return i.size }
+
+// Mode implements fs.FileInfo.
+func (i *memFileInfo) Mode() fs.FileMode { return i.mode }
+
+// ModTime implements fs.FileInfo.
+func (i *memFileInfo) ModTime() time.Time { return i.modTime }
+
+// IsDir implements fs.FileInfo.
+func (i *memFileInfo) IsDir() bool { return false }
+
+// Sys implements fs.FileInfo.
+func (i *memFileInfo) Sys() any { return nil }
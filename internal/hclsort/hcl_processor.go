@@ -29,48 +29,14 @@ func ParseHCLContent(
 	return file, nil
 }
 
-// sortRequiredProvidersInBlock sorts the entries in any required_providers block.
-func sortRequiredProvidersInBlock(block *hclwrite.Block) {
-	for _, b := range block.Body().Blocks() {
-		if b.Type() != "required_providers" {
-			continue
-		}
-		body := b.Body()
-		attrs := body.Attributes()
-
-		providerNames := make([]string, 0, len(attrs))
-		for name := range attrs {
-			providerNames = append(providerNames, name)
-		}
-		sort.Strings(providerNames)
-
-		body.Clear()
-		body.AppendNewline()
-
-		for i, name := range providerNames {
-			attr := attrs[name]
-			tokens := attr.BuildTokens(nil)
-
-			start, end := 0, len(tokens)
-			for start < end && tokens[start].Type == hclsyntax.TokenNewline {
-				start++
-			}
-			for end > start && tokens[end-1].Type == hclsyntax.TokenNewline {
-				end--
-			}
-			body.AppendUnstructuredTokens(tokens[start:end])
-			if i+1 < len(providerNames) {
-				body.AppendNewline()
-			}
-		}
-		body.AppendNewline()
-	}
-}
-
-// sortLocalsBlock sorts the top‐level assignments in a locals block.
-func sortLocalsBlock(block *hclwrite.Block) {
-	body := block.Body()
+// sortBodyAttributes sorts the attributes of body by name. Nested blocks follow the
+// attributes, in their original order.
+func sortBodyAttributes(body *hclwrite.Body) {
 	attrs := body.Attributes()
+	if len(attrs) == 0 {
+		return
+	}
+	blocks := body.Blocks()
 
 	names := make([]string, 0, len(attrs))
 	for name := range attrs {
@@ -80,9 +46,20 @@ func sortLocalsBlock(block *hclwrite.Block) {
 
 	body.Clear()
 	body.AppendNewline()
+	appendAttributes(body, attrs, names)
+	body.AppendNewline()
+	for i, block := range blocks {
+		if i > 0 || len(names) > 0 {
+			body.AppendNewline()
+		}
+		body.AppendBlock(block)
+	}
+}
+
+// appendAttributes appends the named attributes to body, one per line.
+func appendAttributes(body *hclwrite.Body, attrs map[string]*hclwrite.Attribute, names []string) {
 	for i, name := range names {
-		attr := attrs[name]
-		tokens := attr.BuildTokens(nil)
+		tokens := attrs[name].BuildTokens(nil)
 
 		start, end := 0, len(tokens)
 		for start < end && tokens[start].Type == hclsyntax.TokenNewline {
@@ -97,7 +74,6 @@ func sortLocalsBlock(block *hclwrite.Block) {
 			body.AppendNewline()
 		}
 	}
-	body.AppendNewline()
 }
 
 // ProcessAndSortBlocks extracts sortable blocks (variables, outputs, locals, terraform) and sorts them.
@@ -105,49 +81,42 @@ func ProcessAndSortBlocks(
 	file *hclwrite.File,
 	allowedBlocks map[string]bool,
 ) *hclwrite.File {
-	for _, block := range file.Body().Blocks() {
-		switch block.Type() {
-		case "terraform":
-			sortRequiredProvidersInBlock(block)
-		case "locals":
-			sortLocalsBlock(block)
-		}
-	}
+	return SortBlocks(file, TerraformProfile(allowedBlocks))
+}
 
+// SortBlocks sorts the top-level blocks of file and the attributes that profile selects.
+// Nested blocks are left in their order. Top-level attributes are only kept when
+// the profile sorts them.
+func SortBlocks(file *hclwrite.File, profile Profile) *hclwrite.File {
 	body := file.Body()
-	originalBlocks := body.Blocks()
-
-	sortableItems := make([]*SortableBlock, 0)
-	otherBlocks := make([]*hclwrite.Block, 0)
-
-	for _, block := range originalBlocks {
-		blockType := block.Type()
-		if allowedBlocks[blockType] && len(block.Labels()) > 0 {
-			sortableItems = append(sortableItems, &SortableBlock{
-				Name:  block.Labels()[0],
-				Block: block,
-			})
-		} else {
-			otherBlocks = append(otherBlocks, block)
-		}
+	for _, target := range profile.writeAttributeBodies(body) {
+		sortBodyAttributes(target)
 	}
 
-	sort.Slice(sortableItems, func(i, j int) bool {
-		return sortableItems[i].Name < sortableItems[j].Name
-	})
+	blocks := body.Blocks()
+	order := profile.writeOrder(blocks)
+	attrs := body.Attributes()
+	var names []string
+	if profile.sortsAttributes(nil) {
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
 
 	body.Clear()
 
-	for i, block := range otherBlocks {
-		body.AppendBlock(block)
-		if i < len(otherBlocks)-1 || len(sortableItems) > 0 {
+	appendAttributes(body, attrs, names)
+	if len(names) > 0 {
+		body.AppendNewline()
+		if len(blocks) > 0 {
 			body.AppendNewline()
 		}
 	}
 
-	for i, sb := range sortableItems {
-		body.AppendBlock(sb.Block)
-		if i < len(sortableItems)-1 {
+	for i, idx := range order {
+		body.AppendBlock(blocks[idx])
+		if i < len(order)-1 {
 			body.AppendNewline()
 		}
 	}
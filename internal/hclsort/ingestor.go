@@ -1,8 +1,10 @@
 package hclsort
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"os"
 )
 
@@ -10,14 +12,18 @@ import (
 func NewIngestor() *Ingestor {
 	return &Ingestor{
 		AllowedTypes: map[string]bool{
-			"tf":   true,
-			"hcl":  true,
-			"tofu": true,
+			"tf":    true,
+			"hcl":   true,
+			"tofu":  true,
+			"nomad": true,
 		},
 		AllowedBlocks: map[string]bool{
 			"variable": true,
 			"output":   true,
 		},
+		SymlinkPolicy: SymlinkFollow,
+		LineEndings:   LineEndingsAuto,
+		Logger:        log.New(os.Stderr, "", 0),
 	}
 }
 
@@ -33,28 +39,159 @@ func (i *Ingestor) Parse(
 	filenameForParser := inputPath
 
 	if isStdin {
-		src, err = io.ReadAll(os.Stdin)
+		src, err = io.ReadAll(i.stdin())
 		if err != nil {
 			return fmt.Errorf("error reading from stdin: %w", err)
 		}
 	} else {
 		if extErr := CheckFileExtension(inputPath, i.AllowedTypes); extErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: %v\n", extErr)
+			i.logf("Warning: %v", extErr)
 		}
-		src, err = ReadFileBytes(inputPath)
+		if outputPath == "" && !dryRun {
+			skip, linkErr := CheckSymlink(i.fileSystem(), inputPath, i.SymlinkPolicy)
+			if linkErr != nil {
+				return linkErr
+			}
+			if skip {
+				i.logf("Skipping symlink %s", inputPath)
+				return nil
+			}
+		}
+		src, err = ReadFileBytes(i.fileSystem(), inputPath)
 		if err != nil {
 			return err
 		}
 	}
 
-	hclFile, err := ParseHCLContent(src, filenameForParser)
+	sortedBytes, err := i.Sort(src, filenameForParser)
 	if err != nil {
 		return err
 	}
 
-	processedFile := ProcessAndSortBlocks(hclFile, i.AllowedBlocks)
+	return writeContent(i.fileSystem(), i.stdout(), inputPath, outputPath, dryRun, sortedBytes, isStdin)
+}
+
+// SortFile sorts the file at path in place, following the symlink policy. Files that
+// are already sorted, skipped symlinks and files that SkipFile leaves alone are not
+// written. It reports whether the file was rewritten.
+func (i *Ingestor) SortFile(path string) (bool, error) {
+	if skip, reason := i.SkipFile(path); skip {
+		i.logf("Warning: skipping %s: %s", path, reason)
+		return false, nil
+	}
+
+	skip, err := CheckSymlink(i.fileSystem(), path, i.SymlinkPolicy)
+	if err != nil || skip {
+		return false, err
+	}
+
+	src, err := ReadFileBytes(i.fileSystem(), path)
+	if err != nil {
+		return false, err
+	}
+
+	sortedBytes, err := i.Sort(src, path)
+	if err != nil || bytes.Equal(src, sortedBytes) {
+		return false, err
+	}
+
+	if err = i.fileSystem().WriteFile(path, sortedBytes); err != nil {
+		return false, fmt.Errorf("error writing output to file '%s': %w", path, err)
+	}
+	return true, nil
+}
+
+// CheckSorted reads the file at inputPath, or stdin when isStdin is set, and reports
+// whether it is already sorted. The parsed file is returned for further checks.
+func (i *Ingestor) CheckSorted(inputPath string, isStdin bool) (*ModuleFile, bool, error) {
+	var src []byte
+	var err error
+	if isStdin {
+		if src, err = io.ReadAll(i.stdin()); err != nil {
+			return nil, false, fmt.Errorf("error reading from stdin: %w", err)
+		}
+	} else if src, err = ReadFileBytes(i.fileSystem(), inputPath); err != nil {
+		return nil, false, err
+	}
+
+	sortedBytes, err := i.Sort(src, inputPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	file, err := NewModuleFile(inputPath, src)
+	if err != nil {
+		return nil, false, err
+	}
+	return file, bytes.Equal(src, sortedBytes), nil
+}
+
+// Sort runs the sorting pipeline on src and returns the content to write back.
+// It performs no I/O, so it is safe to call concurrently on distinct inputs.
+func (i *Ingestor) Sort(src []byte, filename string) ([]byte, error) {
+	style := DetectTextStyle(src).WithLineEndings(i.LineEndings)
+	normalized := style.Normalize(src)
+	if i.MergeLocals {
+		var err error
+		if normalized, err = mergeLocals(normalized, filename); err != nil {
+			return nil, err
+		}
+	}
+
+	var sortedBytes []byte
+	if i.NoFormat {
+		var err error
+		sortedBytes, err = sortPreservingFormat(normalized, filename, i.Profile(filename))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		profile := i.Profile(filename)
+		nested, err := sortNested(normalized, filename, profile, false)
+		if err != nil {
+			return nil, err
+		}
+		hclFile, err := ParseHCLContent(nested, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		processedFile := SortBlocks(hclFile, profile)
+
+		formattedBytes := FormatHCLBytes(processedFile)
+		sortedBytes = append(bytes.TrimSpace(formattedBytes), '\n')
+	}
+
+	return style.Restore(sortedBytes), nil
+}
 
-	formattedBytes := FormatHCLBytes(processedFile)
+// stdin returns the configured input stream or the process's stdin.
+func (i *Ingestor) stdin() io.Reader {
+	if i.Stdin != nil {
+		return i.Stdin
+	}
+	return os.Stdin
+}
 
-	return WriteSortedContent(inputPath, outputPath, dryRun, formattedBytes, isStdin)
+// stdout returns the configured output stream or the process's stdout.
+func (i *Ingestor) stdout() io.Writer {
+	if i.Stdout != nil {
+		return i.Stdout
+	}
+	return os.Stdout
+}
+
+// logf prints a message to the configured logger. A nil Logger discards it.
+func (i *Ingestor) logf(format string, args ...any) {
+	if i.Logger != nil {
+		i.Logger.Printf(format, args...)
+	}
+}
+
+// fileSystem returns the configured file system or the operating system's.
+func (i *Ingestor) fileSystem() FileSystem {
+	if i.FS != nil {
+		return i.FS
+	}
+	return OSFileSystem{}
 }
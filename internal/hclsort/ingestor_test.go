@@ -0,0 +1,167 @@
+package hclsort_test
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWithInjectedIO(t *testing.T) {
+	t.Parallel()
+
+	const unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	const sorted = "variable \"a\" {}\n\nvariable \"b\" {}\n"
+
+	newIngestor := func(files map[string]string, stdin io.Reader) (*hclsort.Ingestor, *strings.Builder, *strings.Builder) {
+		var stdout, logs strings.Builder
+		ingestor := newMemIngestor(files)
+		ingestor.Stdin = stdin
+		ingestor.Stdout = &stdout
+		ingestor.Logger = log.New(&logs, "", 0)
+		return ingestor, &stdout, &logs
+	}
+
+	t.Run("Rewrite file in memory", func(t *testing.T) {
+		t.Parallel()
+
+		ingestor, stdout, _ := newIngestor(map[string]string{"mod/variables.tf": unsorted}, nil)
+
+		if err := ingestor.Parse("mod/variables.tf", "", false, false); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+
+		got, err := ingestor.FS.ReadFile("mod/variables.tf")
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if diff := cmp.Diff(sorted, string(got)); diff != "" {
+			t.Errorf("unexpected file content (-want +got):\n%s", diff)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("expected nothing on stdout, got:\n%s", stdout.String())
+		}
+	})
+
+	t.Run("Write to output file in memory", func(t *testing.T) {
+		t.Parallel()
+
+		ingestor, _, logs := newIngestor(map[string]string{"in.txt": unsorted}, nil)
+
+		if err := ingestor.Parse("in.txt", "out.tf", false, false); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+
+		got, err := ingestor.FS.ReadFile("out.tf")
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if diff := cmp.Diff(sorted, string(got)); diff != "" {
+			t.Errorf("unexpected file content (-want +got):\n%s", diff)
+		}
+		if !strings.Contains(logs.String(), "not a supported Terraform/HCL type") {
+			t.Errorf("expected extension warning on the logger, got: %q", logs.String())
+		}
+	})
+
+	t.Run("Nil logger discards warnings", func(t *testing.T) {
+		t.Parallel()
+
+		ingestor, _, _ := newIngestor(map[string]string{"in.txt": unsorted}, nil)
+		ingestor.Logger = nil
+
+		if err := ingestor.Parse("in.txt", "out.tf", false, false); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+	})
+
+	t.Run("Stdin to stdout", func(t *testing.T) {
+		t.Parallel()
+
+		ingestor, stdout, _ := newIngestor(nil, strings.NewReader(unsorted))
+
+		if err := ingestor.Parse(hclsort.StdInPathIdentifier, "", false, true); err != nil {
+			t.Fatalf("Parse failed unexpectedly: %v", err)
+		}
+		if diff := cmp.Diff(sorted, stdout.String()); diff != "" {
+			t.Errorf("unexpected stdout (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		t.Parallel()
+
+		ingestor, _, _ := newIngestor(nil, nil)
+
+		err := ingestor.Parse("missing.tf", "", false, false)
+		if err == nil || !strings.Contains(err.Error(), "error reading file") {
+			t.Errorf("expected read error, got: %v", err)
+		}
+	})
+}
+
+func TestSortFile(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"unsorted.tf": "variable \"b\" {}\nvariable \"a\" {}\n",
+		"sorted.tf":   "variable \"a\" {}\n\nvariable \"b\" {}\n",
+	})
+	fsys := ingestor.FS
+
+	for path, wantChanged := range map[string]bool{"unsorted.tf": true, "sorted.tf": false} {
+		before, err := fsys.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+
+		changed, err := ingestor.SortFile(path)
+		if err != nil {
+			t.Fatalf("SortFile(%s) failed: %v", path, err)
+		}
+		if changed != wantChanged {
+			t.Errorf("SortFile(%s) reported changed=%v, want %v", path, changed, wantChanged)
+		}
+
+		after, err := fsys.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if rewritten := !after.ModTime().Equal(before.ModTime()); rewritten != wantChanged {
+			t.Errorf("expected %s to be rewritten=%v", path, wantChanged)
+		}
+	}
+
+	got, err := ingestor.FS.ReadFile("unsorted.tf")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("variable \"a\" {}\n\nvariable \"b\" {}\n", string(got)); diff != "" {
+		t.Errorf("unexpected file content (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckSorted(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"unsorted.tf": "variable \"b\" {}\nvariable \"a\" {}\n",
+		"sorted.tf":   "variable \"a\" {}\n\nvariable \"b\" {}\n",
+	})
+
+	for path, wantSorted := range map[string]bool{"unsorted.tf": false, "sorted.tf": true} {
+		file, sorted, err := ingestor.CheckSorted(path, false)
+		if err != nil {
+			t.Fatalf("CheckSorted(%s) failed: %v", path, err)
+		}
+		if sorted != wantSorted {
+			t.Errorf("CheckSorted(%s) reported sorted=%v, want %v", path, sorted, wantSorted)
+		}
+		if len(file.Body.Blocks) != 2 {
+			t.Errorf("CheckSorted(%s) returned %d blocks, want 2", path, len(file.Body.Blocks))
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package hclsort
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// DuplicateError reports names that are declared more than once where they must be unique.
+type DuplicateError struct {
+	Duplicates []Duplicate
+}
+
+// Error implements the error interface.
+func (e *DuplicateError) Error() string {
+	lines := make([]string, len(e.Duplicates))
+	for i, duplicate := range e.Duplicates {
+		lines[i] = duplicate.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// MergeModuleLocals plans moving the locals blocks of the given files, which belong to
+// one module, into the first file that has one, where they are merged into a single
+// sorted block. Comments travel with the blocks and attributes they precede. A
+// *DuplicateError is returned, and nothing is planned, when a local value is declared
+// more than once. The changes are returned in path order.
+func (i *Ingestor) MergeModuleLocals(paths []string) ([]FileChange, error) {
+	files := map[string]*splitFile{}
+	modFiles := make([]*ModuleFile, 0, len(paths))
+	for _, path := range paths {
+		original, err := ReadFileBytes(i.fileSystem(), path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := NewModuleFile(path, original)
+		if err != nil {
+			return nil, err
+		}
+		modFiles = append(modFiles, file)
+		files[path] = &splitFile{original: original, exists: true, style: DetectTextStyle(original), content: file.Src}
+	}
+	if err := localDuplicates(modFiles); err != nil {
+		return nil, err
+	}
+
+	var target *splitFile
+	var moved []byte
+	for _, file := range modFiles {
+		f := files[file.Path]
+		rest, taken := takeLocalsBlocks(file.Src, file.Body)
+		if len(taken) == 0 {
+			continue
+		}
+		if target == nil {
+			target = f
+			continue
+		}
+		f.content = rest
+		f.touched = true
+		moved = append(moved, taken...)
+	}
+
+	if target == nil {
+		return nil, nil
+	}
+	if len(moved) > 0 {
+		target.content = append(append(trimTrailingBlankLines(target.content), '\n'), moved...)
+	}
+	target.touched = true
+
+	merging := *i
+	merging.MergeLocals = true
+	return merging.splitChanges(files)
+}
+
+// localDuplicates returns a *DuplicateError for the local values that are declared more
+// than once in files.
+func localDuplicates(files []*ModuleFile) error {
+	var found []Duplicate
+	for _, duplicate := range (&Module{Files: files}).Duplicates() {
+		if duplicate.Kind == localValueKind {
+			found = append(found, duplicate)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	return &DuplicateError{Duplicates: found}
+}
+
+// mergeLocals combines the locals blocks of src, which must use LF line endings, into
+// its first locals block. A *DuplicateError is returned when a name is declared twice.
+func mergeLocals(src []byte, filename string) ([]byte, error) {
+	body, err := parseSyntaxBody(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = localDuplicates([]*ModuleFile{{Path: filename, Src: src, Body: body}}); err != nil {
+		return nil, err
+	}
+
+	var blocks []*hclsyntax.Block
+	var items []span
+	floor := 0
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+		if block.Type == "locals" {
+			blocks = append(blocks, block)
+			items = append(items, item)
+		}
+	}
+	if len(blocks) < 2 {
+		return src, nil
+	}
+
+	first := blocks[0]
+	var merged bytes.Buffer
+	merged.Write(src[items[0].start:first.OpenBraceRange.End.Byte])
+	merged.WriteByte('\n')
+	for idx, block := range blocks {
+		if idx > 0 {
+			merged.Write(indentLines(src[items[idx].start:lineStartAt(src, block.Range().Start.Byte)]))
+		}
+		merged.Write(localsContent(src, block))
+	}
+	merged.WriteByte('}')
+	merged.Write(src[first.CloseBraceRange.End.Byte:items[0].end])
+	if !bytes.HasSuffix(merged.Bytes(), []byte("\n")) {
+		merged.WriteByte('\n')
+	}
+
+	texts := make([][]byte, len(items))
+	texts[0] = merged.Bytes()
+	for idx := 1; idx < len(items); idx++ {
+		// The blank lines after a removed block go with it.
+		for items[idx].end < len(src) && src[items[idx].end] == '\n' {
+			items[idx].end++
+		}
+	}
+	out := replaceSpans(src, items, texts)
+	if bytes.HasSuffix(src, []byte("\n")) {
+		// A block removed from the end of the file leaves the blank lines above it behind.
+		out = append(bytes.TrimRight(out, "\n"), '\n')
+	}
+	return out, nil
+}
+
+// takeLocalsBlocks removes the locals blocks of src. It returns the remaining text and
+// the removed blocks, each followed by a blank line.
+func takeLocalsBlocks(src []byte, body *hclsyntax.Body) ([]byte, []byte) {
+	var removed []span
+	var taken []byte
+	floor := 0
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+		if block.Type != "locals" {
+			continue
+		}
+
+		text := append([]byte{}, src[item.start:item.end]...)
+		taken = append(taken, append(trimTrailingBlankLines(text), '\n')...)
+
+		for item.end < len(src) && src[item.end] == '\n' {
+			item.end++
+		}
+		removed = append(removed, item)
+	}
+
+	if len(removed) == 0 {
+		return src, nil
+	}
+	return replaceSpans(src, removed, make([][]byte, len(removed))), taken
+}
+
+// localsContent returns the lines between the braces of a locals block, without the
+// surrounding blank lines. Content on the line of the opening brace is moved to a line
+// of its own.
+func localsContent(src []byte, block *hclsyntax.Block) []byte {
+	inner := src[block.OpenBraceRange.End.Byte:block.CloseBraceRange.Start.Byte]
+	trimmed := bytes.TrimLeft(inner, " \t\n")
+	if len(trimmed) == 0 {
+		return nil
+	}
+
+	text := append([]byte("  "), trimmed...)
+	if lineStart := bytes.LastIndexByte(inner[:len(inner)-len(trimmed)], '\n') + 1; lineStart > 0 {
+		text = append([]byte{}, inner[lineStart:]...)
+	}
+	return trimTrailingBlankLines(text)
+}
+
+// indentLines indents every line of text, which ends with a newline, by two spaces.
+func indentLines(text []byte) []byte {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(text, []byte("\n")) {
+		if len(line) > 0 {
+			buf.WriteString("  ")
+			buf.Write(line)
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,118 @@
+package hclsort_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeLocals(t *testing.T) {
+	t.Parallel()
+
+	src := "# first\nlocals {\n  zeta = 1\n}\n\nvariable \"x\" {}\n\n" +
+		"# second\nlocals { beta = 2 }\n\nlocals {\n  # about alpha\n  alpha = 3\n}\n"
+	want := "# first\nlocals {\n  # about alpha\n  alpha = 3\n  # second\n  beta = 2\n  zeta = 1\n}\n\nvariable \"x\" {}\n"
+
+	for _, noFormat := range []bool{false, true} {
+		ingestor := hclsort.NewIngestor()
+		ingestor.MergeLocals = true
+		ingestor.NoFormat = noFormat
+
+		got, err := ingestor.Sort([]byte(src), "locals.tf")
+		if err != nil {
+			t.Fatalf("Sort(noFormat=%v) failed: %v", noFormat, err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("unexpected output with noFormat=%v (-want +got):\n%s", noFormat, diff)
+		}
+	}
+
+	ingestor := hclsort.NewIngestor()
+	ingestor.MergeLocals = true
+	_, err := ingestor.Sort([]byte("locals {\n  a = 1\n}\n\nlocals {\n  a = 2\n}\n"), "dup.tf")
+	var duplicates *hclsort.DuplicateError
+	if !errors.As(err, &duplicates) {
+		t.Fatalf("expected a DuplicateError, got %v", err)
+	}
+	if diff := cmp.Diff(`dup.tf:6,3: duplicate local value "a", first declared at dup.tf:2,3`, err.Error()); diff != "" {
+		t.Errorf("unexpected error (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeModuleLocals(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"locals.tf": "locals {\n  b = 1\n}\n",
+		"main.tf":   "# shared\nlocals {\n  a = 2\n}\n\nvariable \"x\" {}\n",
+		"other.tf":  "variable \"y\" {}\n",
+	})
+
+	changes, err := ingestor.MergeModuleLocals([]string{"locals.tf", "main.tf", "other.tf"})
+	if err != nil {
+		t.Fatalf("MergeModuleLocals failed: %v", err)
+	}
+	want := []hclsort.FileChange{
+		{
+			Path:   "locals.tf",
+			Before: []byte("locals {\n  b = 1\n}\n"),
+			After:  []byte("locals {\n  # shared\n  a = 2\n  b = 1\n}\n"),
+		},
+		{
+			Path:   "main.tf",
+			Before: []byte("# shared\nlocals {\n  a = 2\n}\n\nvariable \"x\" {}\n"),
+			After:  []byte("variable \"x\" {}\n"),
+		},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+
+	ingestor = newMemIngestor(map[string]string{
+		"a.tf": "locals {\n  name = 1\n}\n",
+		"b.tf": "locals {\n  name = 2\n}\n",
+	})
+	var duplicates *hclsort.DuplicateError
+	if _, err = ingestor.MergeModuleLocals([]string{"a.tf", "b.tf"}); !errors.As(err, &duplicates) {
+		t.Fatalf("expected a DuplicateError, got %v", err)
+	}
+}
+
+func TestMergeModuleLocalsSkipsFilteredFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"locals.tf": "locals {\n  b = 1\n}\n",
+		"main.tf":   "locals {\n  a = 2\n}\n",
+		"gen.tf":    "locals {\n  generated = 3\n}\n",
+	})
+	filter, err := hclsort.NewPathFilter(nil, []string{"gen.tf"}, false)
+	if err != nil {
+		t.Fatalf("NewPathFilter failed: %v", err)
+	}
+
+	paths, err := filter.ModuleFiles(dir, dir)
+	if err != nil {
+		t.Fatalf("ModuleFiles failed: %v", err)
+	}
+	changes, err := hclsort.NewIngestor().MergeModuleLocals(paths)
+	if err != nil {
+		t.Fatalf("MergeModuleLocals failed: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, change := range changes {
+		got[filepath.Base(change.Path)] = string(change.After)
+	}
+	want := map[string]string{
+		"locals.tf": "locals {\n  a = 2\n  b = 1\n}\n",
+		"main.tf":   "",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+}
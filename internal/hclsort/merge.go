@@ -0,0 +1,187 @@
+package hclsort
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// MergeConflictError reports the top-level items that were changed differently on
+// both sides of a merge.
+type MergeConflictError struct {
+	Items []string
+}
+
+// Error implements the error interface.
+func (e *MergeConflictError) Error() string {
+	return "conflicting changes to " + strings.Join(e.Items, ", ")
+}
+
+// mergeItem is a top-level block or attribute of one version of a merged file.
+type mergeItem struct {
+	// key identifies the item across versions, e.g. `variable "region"`.
+	key string
+	// text is the item's source, including its leading comments.
+	text []byte
+	// formatted is text in canonical formatting, used to compare versions.
+	formatted []byte
+}
+
+// Merge performs a three-way merge of the top-level blocks and attributes of base, ours
+// and theirs. Items are matched by block type and labels, or by attribute name; an item
+// changed, added or removed on one side only takes that side's version. The merged file
+// is sorted and keeps the text style of ours. A *MergeConflictError is returned when the
+// same item was changed differently on both sides.
+func (i *Ingestor) Merge(base, ours, theirs []byte, filename string) ([]byte, error) {
+	style := DetectTextStyle(ours).WithLineEndings(i.LineEndings)
+
+	var versions [3][]mergeItem
+	for idx, src := range [][]byte{base, ours, theirs} {
+		items, err := mergeItems(DetectTextStyle(src).Normalize(src), filename)
+		if err != nil {
+			return nil, err
+		}
+		versions[idx] = items
+	}
+
+	merged, conflicts := mergeVersions(versions[0], versions[1], versions[2])
+	if len(conflicts) > 0 {
+		return nil, &MergeConflictError{Items: conflicts}
+	}
+
+	var buf bytes.Buffer
+	for _, item := range merged {
+		buf.Write(item.text)
+		buf.WriteString("\n\n")
+	}
+
+	return i.sortWithStyle(buf.Bytes(), filename, style)
+}
+
+// sortWithStyle sorts src, which must use LF line endings, and gives the result style.
+func (i *Ingestor) sortWithStyle(src []byte, filename string, style TextStyle) ([]byte, error) {
+	lf := *i
+	lf.LineEndings = LineEndingsLF
+	sorted, err := lf.Sort(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	return style.Restore(sorted), nil
+}
+
+// mergeVersions merges the items of the three versions. The result follows the order of
+// ours, followed by the items that only theirs added. It also returns the keys of the
+// conflicting items.
+func mergeVersions(base, ours, theirs []mergeItem) ([]mergeItem, []string) {
+	baseByKey := itemsByKey(base)
+	oursByKey := itemsByKey(ours)
+	theirsByKey := itemsByKey(theirs)
+
+	keys := make([]string, 0, len(ours)+len(theirs))
+	for _, item := range ours {
+		keys = append(keys, item.key)
+	}
+	for _, item := range theirs {
+		if _, ok := oursByKey[item.key]; !ok {
+			keys = append(keys, item.key)
+		}
+	}
+
+	var merged []mergeItem
+	var conflicts []string
+	for _, key := range keys {
+		b, o, t := baseByKey[key], oursByKey[key], theirsByKey[key]
+		switch {
+		case sameItem(o, t), sameItem(b, t):
+			if o != nil {
+				merged = append(merged, *o)
+			}
+		case sameItem(b, o):
+			if t != nil {
+				merged = append(merged, *t)
+			}
+		default:
+			conflicts = append(conflicts, key)
+		}
+	}
+
+	return merged, conflicts
+}
+
+// mergeItems splits src into its top-level blocks and attributes, in source order.
+func mergeItems(src []byte, filename string) ([]mergeItem, error) {
+	file, err := ParseHCLContent(src, filename)
+	if err != nil {
+		return nil, err
+	}
+	body := file.Body()
+
+	// Nodes share their tokens with the file, so a token's index in the file gives the
+	// position of the item it starts.
+	position := map[*hclwrite.Token]int{}
+	for idx, token := range body.BuildTokens(nil) {
+		position[token] = idx
+	}
+
+	type positioned struct {
+		pos  int
+		item mergeItem
+	}
+	var found []positioned
+	add := func(key string, tokens hclwrite.Tokens) {
+		pos := len(position)
+		if len(tokens) > 0 {
+			pos = position[tokens[0]]
+		}
+		text := bytes.TrimSpace(tokens.Bytes())
+		found = append(found, positioned{pos: pos, item: mergeItem{
+			key:       key,
+			text:      text,
+			formatted: bytes.TrimSpace(hclwrite.Format(text)),
+		}})
+	}
+
+	for name, attr := range body.Attributes() {
+		add(name, attr.BuildTokens(nil))
+	}
+	seen := map[string]int{}
+	for _, block := range body.Blocks() {
+		key := block.Type()
+		for _, label := range block.Labels() {
+			key += fmt.Sprintf(" %q", label)
+		}
+		// Repeated blocks, such as several locals blocks, are matched by occurrence.
+		if seen[key]++; seen[key] > 1 {
+			key += fmt.Sprintf(" #%d", seen[key])
+		}
+		add(key, block.BuildTokens(nil))
+	}
+
+	sort.SliceStable(found, func(a, b int) bool { return found[a].pos < found[b].pos })
+	items := make([]mergeItem, len(found))
+	for idx, f := range found {
+		items[idx] = f.item
+	}
+	return items, nil
+}
+
+// itemsByKey indexes items by their key.
+func itemsByKey(items []mergeItem) map[string]*mergeItem {
+	byKey := make(map[string]*mergeItem, len(items))
+	for idx := range items {
+		byKey[items[idx].key] = &items[idx]
+	}
+	return byKey
+}
+
+// sameItem reports whether two versions of an item are equal. A missing item only
+// equals another missing item.
+func sameItem(a, b *mergeItem) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return bytes.Equal(a.formatted, b.formatted)
+}
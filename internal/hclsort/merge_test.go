@@ -0,0 +1,74 @@
+package hclsort_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	const base = "variable \"a\" {}\n\nvariable \"m\" {}\n\nvariable \"old\" {}\n"
+
+	tests := []struct {
+		name     string
+		ours     string
+		theirs   string
+		want     string
+		conflict []string
+	}{
+		{
+			name:   "Additions on both sides",
+			ours:   base + "\nvariable \"b\" {}\n",
+			theirs: base + "\nvariable \"x\" {}\n",
+			want: "variable \"a\" {}\n\nvariable \"b\" {}\n\nvariable \"m\" {}\n\n" +
+				"variable \"old\" {}\n\nvariable \"x\" {}\n",
+		},
+		{
+			name:   "Change on one side and removal on the other",
+			ours:   "variable \"a\" {\n  type = string\n}\n\nvariable \"m\" {}\n\nvariable \"old\" {}\n",
+			theirs: "variable \"a\" {}\n\nvariable \"m\" {}\n",
+			want:   "variable \"a\" {\n  type = string\n}\n\nvariable \"m\" {}\n",
+		},
+		{
+			name:   "Keeps the line endings of ours",
+			ours:   "variable \"a\" {}\r\n\r\nvariable \"m\" {}\r\n\r\nvariable \"old\" {}\r\n",
+			theirs: base + "\nvariable \"b\" {}\n",
+			want: "variable \"a\" {}\r\n\r\nvariable \"b\" {}\r\n\r\nvariable \"m\" {}\r\n\r\n" +
+				"variable \"old\" {}\r\n",
+		},
+		{
+			name:     "Same block changed on both sides",
+			ours:     "variable \"a\" {\n  type = string\n}\n\nvariable \"m\" {}\n\nvariable \"old\" {}\n",
+			theirs:   "variable \"a\" {\n  type = number\n}\n\nvariable \"m\" {}\n\nvariable \"old\" {}\n",
+			conflict: []string{`variable "a"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hclsort.NewIngestor().Merge([]byte(base), []byte(tc.ours), []byte(tc.theirs), "variables.tf")
+			if tc.conflict != nil {
+				var conflictErr *hclsort.MergeConflictError
+				if !errors.As(err, &conflictErr) {
+					t.Fatalf("expected *MergeConflictError, got %v", err)
+				}
+				if diff := cmp.Diff(tc.conflict, conflictErr.Items); diff != "" {
+					t.Errorf("unexpected conflicting items (-want +got):\n%s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("unexpected merge result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package hclsort
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ModuleFile is a parsed file of a module.
+type ModuleFile struct {
+	Path string
+	// Src is the file content with LF line endings and no byte order mark.
+	Src  []byte
+	Body *hclsyntax.Body
+}
+
+// localValueKind is the Duplicate kind of local values.
+const localValueKind = "local value"
+
+// RequiredProviderKind is the Duplicate kind of required_providers entries.
+const RequiredProviderKind = "required provider"
+
+// Module is the set of files in one directory that Terraform loads together.
+type Module struct {
+	Files []*ModuleFile
+}
+
+// Duplicate is a name that is declared more than once where it must be unique.
+type Duplicate struct {
+	// Kind is what was declared twice, e.g. "variable" or "local value".
+	Kind string
+	Name string
+	// First and Second are the positions of the two declarations.
+	First  hcl.Range
+	Second hcl.Range
+}
+
+// String formats the duplicate with both of its positions.
+func (d Duplicate) String() string {
+	return fmt.Sprintf("%s: duplicate %s %q, first declared at %s",
+		formatPos(d.Second), d.Kind, d.Name, formatPos(d.First))
+}
+
+// Variable is a variable block of a module.
+type Variable struct {
+	Name string
+	// Type is the source text of the type constraint, or "any" when there is none.
+	Type string
+	// Required is set when the variable has no default.
+	Required bool
+	Range    hcl.Range
+}
+
+// ModuleFiles returns the Terraform and OpenTofu files directly inside dir, in name order.
+// Like OpenTofu, it leaves out foo.tf when foo.tofu exists. Override files are left out
+// because they redeclare blocks on purpose.
+func ModuleFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory '%s': %w", dir, err)
+	}
+
+	names := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names[entry.Name()] = true
+		}
+	}
+
+	paths := []string{}
+	for name := range names {
+		ext := filepath.Ext(name)
+		if IsOverrideFile(name) {
+			continue
+		}
+		if ext == ".tofu" || (ext == ".tf" && !names[strings.TrimSuffix(name, ext)+".tofu"]) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// TargetFile returns the file of the module in dir, whose files are paths as returned by
+// ModuleFiles, that blocks conventionally kept in base, such as "variables", are added to.
+// That is base.tofu when it exists, or when the module has .tofu files but no base.tf,
+// and base.tf otherwise, so that OpenTofu never ignores the file.
+func TargetFile(dir, base string, paths []string) string {
+	usesTofu := false
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if name == base+".tofu" || name == base+".tf" {
+			return path
+		}
+		usesTofu = usesTofu || filepath.Ext(name) == ".tofu"
+	}
+	if usesTofu {
+		return filepath.Join(dir, base+".tofu")
+	}
+	return filepath.Join(dir, base+".tf")
+}
+
+// IsOverrideFile reports whether path is a Terraform or OpenTofu override file, named
+// override.tf or ending in _override.tf, whose blocks are merged into the blocks of the
+// same name elsewhere in the module.
+func IsOverrideFile(path string) bool {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext != ".tf" && ext != ".tofu" {
+		return false
+	}
+	stem := strings.TrimSuffix(base, ext)
+	return stem == "override" || strings.HasSuffix(stem, "_override")
+}
+
+// ShadowingFile returns the OpenTofu file that replaces the Terraform file at path:
+// OpenTofu ignores foo.tf when foo.tofu exists in the same directory.
+func (i *Ingestor) ShadowingFile(path string) (string, bool) {
+	if filepath.Ext(path) != ".tf" {
+		return "", false
+	}
+	tofu := strings.TrimSuffix(path, ".tf") + ".tofu"
+	if _, err := i.fileSystem().Stat(tofu); err != nil {
+		return "", false
+	}
+	return tofu, true
+}
+
+// SkipFile reports whether the file at path is left alone: because OpenTofu would not load
+// it as it is, or because it is an override file and IncludeOverrides is not set. When it
+// is, the returned string describes the reason.
+func (i *Ingestor) SkipFile(path string) (bool, string) {
+	if tofu, shadowed := i.ShadowingFile(path); shadowed {
+		return true, fmt.Sprintf("OpenTofu ignores it because %s exists", tofu)
+	}
+	if !i.IncludeOverrides && IsOverrideFile(path) {
+		return true, "it is an override file"
+	}
+	return false, ""
+}
+
+// LoadModule reads and parses the given files, which belong to one module.
+func (i *Ingestor) LoadModule(paths []string) (*Module, error) {
+	module := &Module{}
+	for _, path := range paths {
+		src, err := ReadFileBytes(i.fileSystem(), path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := NewModuleFile(path, src)
+		if err != nil {
+			return nil, err
+		}
+		module.Files = append(module.Files, file)
+	}
+	return module, nil
+}
+
+// NewModuleFile parses src as the file at path.
+func NewModuleFile(path string, src []byte) (*ModuleFile, error) {
+	src = DetectTextStyle(src).Normalize(src)
+	body, err := parseSyntaxBody(src, path)
+	if err != nil {
+		return nil, err
+	}
+	return &ModuleFile{Path: path, Src: src, Body: body}, nil
+}
+
+// Duplicates reports the variables, outputs, local values and required providers that
+// are declared more than once in the module, in the order of their second declaration.
+func (m *Module) Duplicates() []Duplicate {
+	var duplicates []Duplicate
+	first := map[[2]string]hcl.Range{}
+	declare := func(kind, name string, rng hcl.Range) {
+		key := [2]string{kind, name}
+		if prev, ok := first[key]; ok {
+			duplicates = append(duplicates, Duplicate{Kind: kind, Name: name, First: prev, Second: rng})
+			return
+		}
+		first[key] = rng
+	}
+
+	for _, file := range m.Files {
+		for _, block := range file.Body.Blocks {
+			switch block.Type {
+			case "variable", "output":
+				if len(block.Labels) > 0 {
+					declare(block.Type, block.Labels[0], block.DefRange())
+				}
+			case "locals":
+				for _, attr := range attributesInSourceOrder(block.Body) {
+					declare(localValueKind, attr.Name, attr.NameRange)
+				}
+			case "terraform":
+				for _, nested := range block.Body.Blocks {
+					if nested.Type != "required_providers" {
+						continue
+					}
+					for _, attr := range attributesInSourceOrder(nested.Body) {
+						declare(RequiredProviderKind, attr.Name, attr.NameRange)
+					}
+				}
+			}
+		}
+	}
+	return duplicates
+}
+
+// Variables returns the variables of the module in declaration order: by file, then by
+// position in the file.
+func (m *Module) Variables() []Variable {
+	var variables []Variable
+	for _, file := range m.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type != "variable" || len(block.Labels) == 0 {
+				continue
+			}
+			v := Variable{Name: block.Labels[0], Type: "any", Range: block.DefRange()}
+			if attr, ok := block.Body.Attributes["type"]; ok {
+				rng := attr.Expr.Range()
+				v.Type = string(file.Src[rng.Start.Byte:rng.End.Byte])
+			}
+			_, hasDefault := block.Body.Attributes["default"]
+			v.Required = !hasDefault
+			variables = append(variables, v)
+		}
+	}
+	return variables
+}
+
+// formatPos formats the start of rng as "file:line,column".
+func formatPos(rng hcl.Range) string {
+	return fmt.Sprintf("%s:%d,%d", rng.Filename, rng.Start.Line, rng.Start.Column)
+}
@@ -0,0 +1,161 @@
+package hclsort_test
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModuleDuplicates(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"main.tf": "variable \"region\" {}\n\nlocals {\n  name = \"a\"\n}\n\n" +
+			"terraform {\n  required_providers {\n    aws = {}\n  }\n}\n",
+		"other.tf": "locals {\n  name = \"b\"\n  tags = {}\n}\n\nvariable \"region\" {}\n\noutput \"id\" {}\n\n" +
+			"terraform {\n  required_providers {\n    aws = {}\n  }\n}\n",
+	})
+
+	module, err := ingestor.LoadModule([]string{"main.tf", "other.tf"})
+	if err != nil {
+		t.Fatalf("LoadModule failed: %v", err)
+	}
+
+	var got []string
+	for _, duplicate := range module.Duplicates() {
+		got = append(got, duplicate.String())
+	}
+	want := []string{
+		`other.tf:2,3: duplicate local value "name", first declared at main.tf:4,3`,
+		`other.tf:6,1: duplicate variable "region", first declared at main.tf:1,1`,
+		`other.tf:12,5: duplicate required provider "aws", first declared at main.tf:9,5`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected duplicates (-want +got):\n%s", diff)
+	}
+}
+
+func TestShadowedTerraformFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"main.tf", "main.tofu", "variables.tf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("\n"), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	paths, err := hclsort.ModuleFiles(dir)
+	if err != nil {
+		t.Fatalf("ModuleFiles failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "main.tofu"), filepath.Join(dir, "variables.tf")}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("unexpected module files (-want +got):\n%s", diff)
+	}
+
+	ingestor := hclsort.NewIngestor()
+	if tofu, shadowed := ingestor.ShadowingFile(filepath.Join(dir, "main.tf")); !shadowed ||
+		tofu != filepath.Join(dir, "main.tofu") {
+		t.Errorf("expected main.tf to be shadowed by main.tofu, got %q, %v", tofu, shadowed)
+	}
+	if _, shadowed := ingestor.ShadowingFile(filepath.Join(dir, "variables.tf")); shadowed {
+		t.Error("expected variables.tf not to be shadowed")
+	}
+
+	const unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	watched := newMemIngestor(map[string]string{"main.tf": unsorted, "main.tofu": "\n"})
+	var logs strings.Builder
+	watched.Logger = log.New(&logs, "", 0)
+	if changed, sortErr := watched.SortFile("main.tf"); sortErr != nil || changed {
+		t.Errorf("expected SortFile to skip the shadowed file, got %v, %v", changed, sortErr)
+	}
+	if got, _ := watched.FS.ReadFile("main.tf"); string(got) != unsorted {
+		t.Errorf("expected main.tf to be left alone, got:\n%s", got)
+	}
+	if !strings.Contains(logs.String(), "OpenTofu ignores it") {
+		t.Errorf("expected a warning about the shadowed file, got %q", logs.String())
+	}
+}
+
+func TestOverrideFiles(t *testing.T) {
+	t.Parallel()
+
+	for path, want := range map[string]bool{
+		"override.tf":               true,
+		"dir/override.tofu":         true,
+		"variables_override.tf":     true,
+		"main.tf":                   false,
+		"overrides.tf":              false,
+		"variables_override.tfvars": false,
+	} {
+		if got := hclsort.IsOverrideFile(path); got != want {
+			t.Errorf("IsOverrideFile(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"main.tf", "main_override.tf", "override.tofu"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("\n"), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	paths, err := hclsort.ModuleFiles(dir)
+	if err != nil {
+		t.Fatalf("ModuleFiles failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(dir, "main.tf")}, paths); diff != "" {
+		t.Errorf("unexpected module files (-want +got):\n%s", diff)
+	}
+
+	const unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	for _, include := range []bool{false, true} {
+		ingestor := newMemIngestor(map[string]string{"main_override.tf": unsorted})
+		ingestor.IncludeOverrides = include
+		changed, sortErr := ingestor.SortFile("main_override.tf")
+		if sortErr != nil || changed != include {
+			t.Errorf("SortFile with IncludeOverrides=%v = %v, %v, want %v", include, changed, sortErr, include)
+		}
+	}
+}
+
+func TestTargetFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{name: "Terraform module", files: []string{"main.tf"}, want: "variables.tf"},
+		{name: "OpenTofu module", files: []string{"main.tofu"}, want: "variables.tofu"},
+		{name: "Existing Terraform file", files: []string{"main.tofu", "variables.tf"}, want: "variables.tf"},
+		{name: "Shadowed Terraform file", files: []string{"main.tf", "variables.tf", "variables.tofu"}, want: "variables.tofu"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			files := map[string]string{}
+			for _, name := range tc.files {
+				files[name] = "\n"
+			}
+			writeFiles(t, dir, files)
+
+			paths, err := hclsort.ModuleFiles(dir)
+			if err != nil {
+				t.Fatalf("ModuleFiles failed: %v", err)
+			}
+			if got := hclsort.TargetFile(dir, "variables", paths); got != filepath.Join(dir, tc.want) {
+				t.Errorf("TargetFile() = %s, want %s", got, filepath.Join(dir, tc.want))
+			}
+		})
+	}
+}
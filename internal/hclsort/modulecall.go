@@ -0,0 +1,85 @@
+package hclsort
+
+import (
+	"bytes"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ModuleMetaArguments returns the arguments of a module block that are not input
+// variables, in the order OrderModuleArguments puts them first.
+func ModuleMetaArguments() []string {
+	return []string{"source", "version", "count", "for_each", "providers", "depends_on"}
+}
+
+// ModuleArgumentOrder returns the order, in the form used by Profile.order, that puts the
+// argument names of a module call in order: the meta-arguments first, then the given
+// variables in their order and last the arguments that are neither.
+func ModuleArgumentOrder(names, variables []string) []int {
+	meta := ModuleMetaArguments()
+	rank := func(name string) int {
+		if idx := slices.Index(meta, name); idx >= 0 {
+			return idx
+		}
+		if idx := slices.Index(variables, name); idx >= 0 {
+			return len(meta) + idx
+		}
+		return len(meta) + len(variables)
+	}
+
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rank(names[order[a]]) < rank(names[order[b]]) })
+	return order
+}
+
+// OrderModuleArguments plans reordering the arguments of the module blocks in the file
+// at path. variables maps the name of a module call to the variables of the called
+// module, in declaration order; other module blocks are left alone. The arguments are
+// ordered by ModuleArgumentOrder and keep their comments. It reports whether anything
+// moved.
+func (i *Ingestor) OrderModuleArguments(path string, variables map[string][]string) (FileChange, bool, error) {
+	original, err := ReadFileBytes(i.fileSystem(), path)
+	if err != nil {
+		return FileChange{}, false, err
+	}
+	style := DetectTextStyle(original)
+	src := style.Normalize(original)
+	body, err := parseSyntaxBody(src, path)
+	if err != nil {
+		return FileChange{}, false, err
+	}
+
+	var regions []span
+	var texts [][]byte
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) == 0 {
+			continue
+		}
+		order, ok := variables[block.Labels[0]]
+		if !ok {
+			continue
+		}
+		orderOf := func(attrs []*hclsyntax.Attribute) []int {
+			names := make([]string, len(attrs))
+			for idx, attr := range attrs {
+				names[idx] = attr.Name
+			}
+			return ModuleArgumentOrder(names, order)
+		}
+		if region, text, moved := reorderAttributesPreserving(src, block.Body, orderOf); moved {
+			regions = append(regions, region)
+			texts = append(texts, text)
+		}
+	}
+	if len(regions) == 0 {
+		return FileChange{}, false, nil
+	}
+
+	after := style.WithLineEndings(i.LineEndings).Restore(replaceSpans(src, regions, texts))
+	return FileChange{Path: path, Before: original, After: after}, !bytes.Equal(after, original), nil
+}
@@ -0,0 +1,33 @@
+package hclsort_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOrderModuleArguments(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"main.tf": "module \"net\" {\n  # Tags.\n  tags   = {}\n  extra  = 1\n  name   = \"a\"\n" +
+			"  source = \"./net\"\n}\n\nmodule \"other\" {\n  source = \"./other\"\n  b      = 1\n}\n",
+	})
+
+	change, moved, err := ingestor.OrderModuleArguments("main.tf", map[string][]string{"net": {"name", "tags"}})
+	if err != nil {
+		t.Fatalf("OrderModuleArguments failed: %v", err)
+	}
+	if !moved {
+		t.Fatal("expected the arguments to move")
+	}
+	want := "module \"net\" {\n  source = \"./net\"\n  name   = \"a\"\n  # Tags.\n  tags   = {}\n  extra  = 1\n" +
+		"}\n\nmodule \"other\" {\n  source = \"./other\"\n  b      = 1\n}\n"
+	if diff := cmp.Diff(want, string(change.After)); diff != "" {
+		t.Errorf("unexpected content (-want +got):\n%s", diff)
+	}
+
+	if _, moved, err = ingestor.OrderModuleArguments("main.tf", map[string][]string{"other": {"b"}}); err != nil || moved {
+		t.Errorf("expected nothing to move for an ordered call, got moved=%v, err=%v", moved, err)
+	}
+}
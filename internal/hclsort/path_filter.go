@@ -0,0 +1,296 @@
+package hclsort
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// IgnoreFileName is the name of the optional file holding gitignore-style rules for tfsort.
+const IgnoreFileName = ".tfsortignore"
+
+// gitignoreFileName is the name of the file holding git's ignore rules.
+const gitignoreFileName = ".gitignore"
+
+// PathFilter decides which paths are skipped while walking directories.
+type PathFilter struct {
+	Include      []string
+	Exclude      []string
+	UseGitignore bool
+	// Logger receives warnings about unreadable ignore files and invalid ignore patterns.
+	// NewPathFilter sets it to a logger writing to the process's stderr; nil discards them.
+	Logger *log.Logger
+
+	rules map[ruleScope][]ignoreRule
+	tops  map[string]string
+}
+
+// ruleScope identifies the ignore rules in effect for a directory when reading stops at top.
+type ruleScope struct {
+	dir string
+	top string
+}
+
+// ignoreRule is a single parsed line of a gitignore-style file.
+type ignoreRule struct {
+	base    string
+	pattern string
+	source  string
+	negate  bool
+	dirOnly bool
+}
+
+// NewPathFilter returns a PathFilter after validating the include and exclude glob patterns.
+func NewPathFilter(include, exclude []string, useGitignore bool) (*PathFilter, error) {
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if !doublestar.ValidatePattern(filepath.ToSlash(pattern)) {
+			return nil, fmt.Errorf("invalid glob pattern '%s'", pattern)
+		}
+	}
+
+	return &PathFilter{
+		Include:      include,
+		Exclude:      exclude,
+		UseGitignore: useGitignore,
+		Logger:       log.New(os.Stderr, "", 0),
+		rules:        map[ruleScope][]ignoreRule{},
+		tops:         map[string]string{},
+	}, nil
+}
+
+// Skip reports whether path, reached while walking root, should be skipped.
+// When it should, the returned string describes the reason.
+func (f *PathFilter) Skip(root, path string, isDir bool) (bool, string) {
+	if skip, reason := f.excluded(root, path, isDir); skip {
+		return true, reason
+	}
+
+	if !isDir && len(f.Include) > 0 {
+		rel := relativeSlashPath(root, path)
+		for _, pattern := range f.Include {
+			if doublestar.MatchUnvalidated(filepath.ToSlash(pattern), rel) {
+				return false, ""
+			}
+		}
+		return true, "does not match any include pattern"
+	}
+
+	return false, ""
+}
+
+// ModuleFiles returns the files of the module in dir, as the package-level ModuleFiles
+// does, without the files the filter skips when walking root.
+func (f *PathFilter) ModuleFiles(root, dir string) ([]string, error) {
+	paths, err := ModuleFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := paths[:0]
+	for _, path := range paths {
+		if skip, _ := f.Skip(root, path, false); !skip {
+			kept = append(kept, path)
+		}
+	}
+	return kept, nil
+}
+
+// excluded reports whether path or one of its parents below root matches an exclude
+// pattern or an ignore rule.
+func (f *PathFilter) excluded(root, path string, isDir bool) (bool, string) {
+	rel := relativeSlashPath(root, path)
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return false, ""
+	}
+
+	if strings.Contains(rel, "/") && filepath.Dir(path) != path {
+		if skip, reason := f.excluded(root, filepath.Dir(path), true); skip {
+			return true, reason
+		}
+	}
+
+	for _, pattern := range f.Exclude {
+		if doublestar.MatchUnvalidated(filepath.ToSlash(pattern), rel) {
+			return true, fmt.Sprintf("matches exclude pattern '%s'", pattern)
+		}
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false, ""
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, ""
+	}
+	if rule := f.matchIgnoreRules(f.topFor(absRoot), absPath, isDir); rule != nil && !rule.negate {
+		return true, "ignored by " + rule.source
+	}
+
+	return false, ""
+}
+
+// relativeSlashPath returns path relative to root using forward slashes.
+// Mixed relative and absolute inputs are resolved against the working directory first.
+func relativeSlashPath(root, path string) string {
+	if absRoot, err := filepath.Abs(root); err == nil {
+		root = absRoot
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchIgnoreRules returns the last ignore rule matching absPath, or nil. Ignore files
+// are read from the directories between absPath and top.
+func (f *PathFilter) matchIgnoreRules(top, absPath string, isDir bool) *ignoreRule {
+	rules := f.rulesFor(filepath.Dir(absPath), top)
+
+	var matched *ignoreRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		rel, err := filepath.Rel(rule.base, absPath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if doublestar.MatchUnvalidated(rule.pattern, filepath.ToSlash(rel)) {
+			matched = rule
+		}
+	}
+
+	return matched
+}
+
+// topFor returns the outermost directory whose ignore files apply to a walk of absRoot:
+// the root of the enclosing repository, or absRoot itself outside a repository.
+func (f *PathFilter) topFor(absRoot string) string {
+	if top, ok := f.tops[absRoot]; ok {
+		return top
+	}
+
+	top := absRoot
+	for dir := absRoot; ; dir = filepath.Dir(dir) {
+		if isRepositoryRoot(dir) {
+			top = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	f.tops[absRoot] = top
+	return top
+}
+
+// rulesFor returns the ignore rules in effect for entries of dir, outermost first.
+// Parent directories are consulted up to top.
+func (f *PathFilter) rulesFor(dir, top string) []ignoreRule {
+	scope := ruleScope{dir: dir, top: top}
+	if rules, ok := f.rules[scope]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	if parent := filepath.Dir(dir); parent != dir && dir != top {
+		rules = append(rules, f.rulesFor(parent, top)...)
+	}
+
+	if f.UseGitignore {
+		rules = append(rules, f.readIgnoreFile(filepath.Join(dir, gitignoreFileName))...)
+	}
+	rules = append(rules, f.readIgnoreFile(filepath.Join(dir, IgnoreFileName))...)
+
+	f.rules[scope] = rules
+	return rules
+}
+
+// isRepositoryRoot reports whether dir contains a .git entry.
+func isRepositoryRoot(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// readIgnoreFile parses a gitignore-style file. Missing or unreadable files yield no rules.
+func (f *PathFilter) readIgnoreFile(path string) []ignoreRule {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			f.warn("Warning: could not read %s: %v", path, err)
+		}
+		return nil
+	}
+
+	return f.parseIgnoreRules(content, filepath.Dir(path), path)
+}
+
+// warn prints a warning to the filter's logger, if any.
+func (f *PathFilter) warn(format string, args ...any) {
+	if f.Logger != nil {
+		f.Logger.Printf(format, args...)
+	}
+}
+
+// parseIgnoreRules converts gitignore syntax into doublestar patterns relative to base.
+func (f *PathFilter) parseIgnoreRules(content []byte, base, source string) []ignoreRule {
+	var rules []ignoreRule
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base, source: source}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+
+		if !doublestar.ValidatePattern(line) {
+			f.warn("Warning: ignoring invalid pattern '%s' in %s", line, source)
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
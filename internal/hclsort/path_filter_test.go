@@ -0,0 +1,121 @@
+package hclsort_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+)
+
+func TestPathFilter(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("vendor/\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+	ignoreContent := []byte("# generated files\n*.gen.tf\n!keep.gen.tf\n")
+	if err := os.WriteFile(filepath.Join(root, hclsort.IgnoreFileName), ignoreContent, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", hclsort.IgnoreFileName, err)
+	}
+
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		gitignore bool
+		path      string
+		isDir     bool
+		wantSkip  bool
+	}{
+		{name: "Plain file", path: "main.tf"},
+		{name: "Excluded directory", exclude: []string{"examples/**"}, path: "examples", isDir: true, wantSkip: true},
+		{name: "File under excluded directory", exclude: []string{"examples"}, path: "examples/a/main.tf", wantSkip: true},
+		{name: "Include pattern matches", include: []string{"modules/**/*.tf"}, path: "modules/x/main.tf"},
+		{name: "Include pattern misses", include: []string{"modules/**/*.tf"}, path: "main.tf", wantSkip: true},
+		{name: "Include does not prune directories", include: []string{"modules/**/*.tf"}, path: "other", isDir: true},
+		{name: "Tfsortignore rule", path: "nested/foo.gen.tf", wantSkip: true},
+		{name: "Tfsortignore negation", path: "nested/keep.gen.tf"},
+		{name: "Gitignore disabled by default", path: "vendor", isDir: true},
+		{name: "Gitignore directory rule", gitignore: true, path: "vendor", isDir: true, wantSkip: true},
+		{name: "Gitignore directory rule skips nested files", gitignore: true, path: "vendor/m/main.tf", wantSkip: true},
+		{name: "Gitignore directory rule ignores files", gitignore: true, path: "vendor.tf"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := hclsort.NewPathFilter(tc.include, tc.exclude, tc.gitignore)
+			if err != nil {
+				t.Fatalf("NewPathFilter failed: %v", err)
+			}
+
+			skip, reason := filter.Skip(root, filepath.Join(root, tc.path), tc.isDir)
+			if skip != tc.wantSkip {
+				t.Errorf("Skip(%q) = %v (%s), want %v", tc.path, skip, reason, tc.wantSkip)
+			}
+			if skip && reason == "" {
+				t.Errorf("Skip(%q) returned no reason", tc.path)
+			}
+		})
+	}
+
+	t.Run("Absolute path outside a relative root", func(t *testing.T) {
+		filter, err := hclsort.NewPathFilter(nil, []string{"examples/**"}, false)
+		if err != nil {
+			t.Fatalf("NewPathFilter failed: %v", err)
+		}
+		if skip, reason := filter.Skip(".", filepath.Join(root, "main.tf"), false); skip {
+			t.Errorf("expected file outside root not to be skipped, got: %s", reason)
+		}
+	})
+
+	t.Run("Ignore files above the walk root outside a repository", func(t *testing.T) {
+		parent := t.TempDir()
+		child := filepath.Join(parent, "child")
+		if err := os.Mkdir(child, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(parent, hclsort.IgnoreFileName), []byte("*.tf\n"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", hclsort.IgnoreFileName, err)
+		}
+
+		filter, err := hclsort.NewPathFilter(nil, nil, false)
+		if err != nil {
+			t.Fatalf("NewPathFilter failed: %v", err)
+		}
+		if skip, reason := filter.Skip(child, filepath.Join(child, "main.tf"), false); skip {
+			t.Errorf("expected ignore file above the walk root to be ignored, got: %s", reason)
+		}
+		if skip, _ := filter.Skip(parent, filepath.Join(child, "main.tf"), false); !skip {
+			t.Error("expected ignore file at the walk root to apply")
+		}
+	})
+
+	t.Run("Warnings go to the logger", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, hclsort.IgnoreFileName), []byte("[a-\n"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", hclsort.IgnoreFileName, err)
+		}
+
+		filter, err := hclsort.NewPathFilter(nil, nil, false)
+		if err != nil {
+			t.Fatalf("NewPathFilter failed: %v", err)
+		}
+		var logs bytes.Buffer
+		filter.Logger = log.New(&logs, "", 0)
+		filter.Skip(dir, filepath.Join(dir, "main.tf"), false)
+		if !bytes.Contains(logs.Bytes(), []byte("ignoring invalid pattern")) {
+			t.Errorf("expected a warning about the invalid pattern, got %q", logs.String())
+		}
+	})
+
+	t.Run("Invalid pattern", func(t *testing.T) {
+		if _, err := hclsort.NewPathFilter(nil, []string{"[a-"}, false); err == nil {
+			t.Error("Expected error for invalid glob pattern but got nil")
+		}
+	})
+}
@@ -0,0 +1,335 @@
+package hclsort
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// span is a half-open byte range of a source file.
+type span struct {
+	start int
+	end   int
+}
+
+// SortPreservingFormat reorders the same blocks and attributes as ProcessAndSortBlocks,
+// but works on the raw source instead of hclwrite tokens. Bytes that belong to items which
+// did not move are left exactly as they were, and blank lines are only normalized next to
+// items that were moved. Leading comment lines travel with the item they precede.
+func SortPreservingFormat(
+	src []byte,
+	filename string,
+	allowedBlocks map[string]bool,
+) ([]byte, error) {
+	return sortPreservingFormat(src, filename, TerraformProfile(allowedBlocks))
+}
+
+// sortPreservingFormat is SortPreservingFormat for the blocks and attributes that
+// profile selects.
+func sortPreservingFormat(src []byte, filename string, profile Profile) ([]byte, error) {
+	// Nested bodies are rewritten first; the file is then parsed again so the
+	// top-level ranges reflect the new content.
+	src, err := sortNested(src, filename, profile, true)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseSyntaxBody(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	floor := 0
+	items := make([]span, 0, len(body.Blocks))
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+		items = append(items, item)
+	}
+	order := profile.syntaxOrder(src, nil, body.Blocks)
+	src = reorderSpans(src, items, order, "\n")
+
+	// Top-level attributes may sit between blocks, so they are sorted last.
+	if profile.sortsAttributes(nil) {
+		if body, err = parseSyntaxBody(src, filename); err != nil {
+			return nil, err
+		}
+		if region, text, changed := sortAttributesPreserving(src, body); changed {
+			src = replaceSpans(src, []span{region}, [][]byte{text})
+		}
+	}
+	return src, nil
+}
+
+// sortNested sorts the nested blocks that profile selects. With preserve, which the
+// NoFormat pipeline sets, it also sorts the attributes of nested bodies and keeps the
+// separators between reordered blocks as they were. Bodies are handled from the deepest
+// level up, and the source is parsed again after each level so no two rewrites overlap.
+func sortNested(src []byte, filename string, profile Profile, preserve bool) ([]byte, error) {
+	depth := 0
+	for _, rule := range profile.Rules {
+		depth = max(depth, len(rule.Path))
+	}
+
+	for ; depth >= 1; depth-- {
+		passes := []func(path []string, body *hclsyntax.Body, src []byte) (span, []byte, bool){
+			func(path []string, body *hclsyntax.Body, src []byte) (span, []byte, bool) {
+				return reorderBlocksPreserving(src, path, body, profile, preserve)
+			},
+		}
+		if preserve {
+			passes = append(passes, func(path []string, body *hclsyntax.Body, src []byte) (span, []byte, bool) {
+				if !profile.sortsAttributes(path) {
+					return span{}, nil, false
+				}
+				return sortAttributesPreserving(src, body)
+			})
+		}
+
+		for _, pass := range passes {
+			body, err := parseSyntaxBody(src, filename)
+			if err != nil {
+				return nil, err
+			}
+			var regions []span
+			var texts [][]byte
+			profile.walkSyntaxBodies(body, nil, func(path []string, nested *hclsyntax.Body) {
+				if len(path) != depth {
+					return
+				}
+				if region, text, changed := pass(path, nested, src); changed {
+					regions = append(regions, region)
+					texts = append(texts, text)
+				}
+			})
+			if len(regions) > 0 {
+				src = replaceSpans(src, regions, texts)
+			}
+		}
+	}
+	return src, nil
+}
+
+// reorderBlocksPreserving orders the blocks of body, at path, as profile does. It returns
+// the region of src covered by the blocks, its replacement and whether anything moved.
+// Attributes between the blocks stay where they are. Blank lines next to moved blocks
+// are normalized unless keepGaps is set.
+func reorderBlocksPreserving(
+	src []byte,
+	path []string,
+	body *hclsyntax.Body,
+	profile Profile,
+	keepGaps bool,
+) (span, []byte, bool) {
+	if len(body.Blocks) < 2 {
+		return span{}, nil, false
+	}
+	order := profile.syntaxOrder(src, path, body.Blocks)
+
+	floor := body.SrcRange.Start.Byte + 1
+	items := make([]span, len(body.Blocks))
+	for i, block := range body.Blocks {
+		items[i] = itemSpan(src, block.Range(), floor)
+		floor = items[i].end
+	}
+
+	region := span{start: items[0].start, end: items[len(items)-1].end}
+	var reordered []byte
+	if keepGaps {
+		texts := make([][]byte, len(items))
+		for i, idx := range order {
+			texts[i] = src[items[idx].start:items[idx].end]
+		}
+		reordered = replaceSpans(src, items, texts)
+	} else {
+		reordered = reorderSpans(src, items, order, "\n")
+	}
+	if bytes.Equal(reordered, src) {
+		return span{}, nil, false
+	}
+	shift := len(reordered) - len(src)
+	return region, reordered[region.start : region.end+shift], true
+}
+
+// attributesInSourceOrder returns the attributes of body in the order they appear.
+func attributesInSourceOrder(body *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(a, b int) bool {
+		return attrs[a].SrcRange.Start.Byte < attrs[b].SrcRange.Start.Byte
+	})
+	return attrs
+}
+
+// attributeOrder returns the order that sorts attrs by name, in the form used by Profile.order.
+func attributeOrder(attrs []*hclsyntax.Attribute) []int {
+	order := make([]int, len(attrs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return attrs[order[a]].Name < attrs[order[b]].Name
+	})
+	return order
+}
+
+// parseSyntaxBody parses src with hclsyntax, which keeps exact byte ranges for every item.
+func parseSyntaxBody(src []byte, filename string) (*hclsyntax.Body, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf(
+			"error parsing HCL content from '%s': %w",
+			filename,
+			diags,
+		)
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("error parsing HCL content from '%s': unexpected body type", filename)
+	}
+	return body, nil
+}
+
+// sortAttributesPreserving sorts the attributes of body by name. It returns the region of
+// src covered by the attributes, its replacement and whether anything moved.
+func sortAttributesPreserving(src []byte, body *hclsyntax.Body) (span, []byte, bool) {
+	return reorderAttributesPreserving(src, body, attributeOrder)
+}
+
+// reorderAttributesPreserving rearranges the attributes of body in the order returned by
+// orderOf for them in source order, like sortAttributesPreserving.
+func reorderAttributesPreserving(
+	src []byte,
+	body *hclsyntax.Body,
+	orderOf func(attrs []*hclsyntax.Attribute) []int,
+) (span, []byte, bool) {
+	attrs := attributesInSourceOrder(body)
+	if len(attrs) < 2 {
+		return span{}, nil, false
+	}
+
+	// A nested body starts with its opening brace; the body of a file starts at 0.
+	floor := body.SrcRange.Start.Byte
+	if floor < len(src) && src[floor] == '{' {
+		floor++
+	}
+	items := make([]span, len(attrs))
+	for i, attr := range attrs {
+		items[i] = itemSpan(src, attr.SrcRange, floor)
+		floor = items[i].end
+	}
+
+	order := orderOf(attrs)
+
+	region := span{start: items[0].start, end: items[len(items)-1].end}
+	reordered := reorderSpans(src, items, order, "")
+	if bytes.Equal(reordered, src) {
+		return span{}, nil, false
+	}
+
+	shift := len(reordered) - len(src)
+	return region, reordered[region.start : region.end+shift], true
+}
+
+// itemSpan widens rng to whole lines: it takes in the comment lines directly above the
+// item and the rest of its last line, without crossing floor.
+func itemSpan(src []byte, rng hcl.Range, floor int) span {
+	item := span{start: rng.Start.Byte, end: rng.End.Byte}
+
+	if lineStart := lineStartAt(src, item.start); lineStart >= floor &&
+		len(bytes.TrimSpace(src[lineStart:item.start])) == 0 {
+		item.start = lineStart
+		for item.start > floor {
+			prevStart := lineStartAt(src, item.start-1)
+			if prevStart < floor || !isCommentLine(src[prevStart:item.start-1]) {
+				break
+			}
+			item.start = prevStart
+		}
+	}
+
+	lineEnd := bytes.IndexByte(src[item.end:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(src) - item.end
+	} else {
+		lineEnd++
+	}
+	if rest := bytes.TrimSpace(src[item.end : item.end+lineEnd]); len(rest) == 0 || isCommentLine(rest) {
+		item.end += lineEnd
+	}
+
+	return item
+}
+
+// lineStartAt returns the offset of the first byte of the line containing offset.
+func lineStartAt(src []byte, offset int) int {
+	return bytes.LastIndexByte(src[:offset], '\n') + 1
+}
+
+// isCommentLine reports whether line holds nothing but a single-line comment.
+func isCommentLine(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return bytes.HasPrefix(line, []byte("#")) ||
+		bytes.HasPrefix(line, []byte("//")) ||
+		(bytes.HasPrefix(line, []byte("/*")) && bytes.HasSuffix(line, []byte("*/")))
+}
+
+// reorderSpans rearranges items so that slot i holds item order[i]. The text between
+// items stays in place; when it is only whitespace and touches a moved item it is
+// replaced by movedGap.
+func reorderSpans(src []byte, items []span, order []int, movedGap string) []byte {
+	moved := false
+	for i, idx := range order {
+		if i != idx {
+			moved = true
+			break
+		}
+	}
+	if !moved {
+		return src
+	}
+
+	var buf bytes.Buffer
+	buf.Write(src[:items[0].start])
+	for i, idx := range order {
+		buf.Write(src[items[idx].start:items[idx].end])
+		if i == len(order)-1 {
+			break
+		}
+
+		gap := src[items[i].end:items[i+1].start]
+		if len(bytes.TrimSpace(gap)) == 0 && (order[i] != i || order[i+1] != i+1) {
+			buf.WriteString(movedGap)
+		} else {
+			buf.Write(gap)
+		}
+	}
+	buf.Write(src[items[len(items)-1].end:])
+
+	return buf.Bytes()
+}
+
+// replaceSpans substitutes texts for the non-overlapping regions of src.
+func replaceSpans(src []byte, regions []span, texts [][]byte) []byte {
+	idx := make([]int, len(regions))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return regions[idx[a]].start < regions[idx[b]].start })
+
+	var buf bytes.Buffer
+	last := 0
+	for _, i := range idx {
+		buf.Write(src[last:regions[i].start])
+		buf.Write(texts[i])
+		last = regions[i].end
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes()
+}
@@ -0,0 +1,44 @@
+package hclsort_test
+
+import (
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSortPreservingFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := testsFromFixtures(t, []string{
+		"no_format",
+		"unchanged",
+	})
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := hclsort.SortPreservingFormat([]byte(tc.hclInput), "test.tf", hclsort.NewIngestor().AllowedBlocks)
+			if err != nil {
+				t.Fatalf("SortPreservingFormat failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("expected output to match expected output, but got:\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Sorted input is left byte for byte", func(t *testing.T) {
+		t.Parallel()
+
+		const input = "variable   \"a\" {\n  type    = string\n}\n\n\n\nvariable \"b\" {}\n"
+		got, err := hclsort.SortPreservingFormat([]byte(input), "test.tf", hclsort.NewIngestor().AllowedBlocks)
+		if err != nil {
+			t.Fatalf("SortPreservingFormat failed: %v", err)
+		}
+		if string(got) != input {
+			t.Errorf("expected input to be unchanged, got:\n%s", got)
+		}
+	})
+}
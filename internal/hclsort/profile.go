@@ -0,0 +1,443 @@
+package hclsort
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Names of the built-in profiles.
+const (
+	ProfileTerraform  = "terraform"
+	ProfilePacker     = "packer"
+	ProfilePackerVars = "packer-vars"
+	ProfileNomad      = "nomad"
+	ProfileTest       = "test"
+)
+
+// KeyPart is one part of the key blocks are sorted by: a label, by index, or the value
+// of an attribute.
+type KeyPart struct {
+	Label int
+	// Attribute names the attribute whose value is used instead of a label.
+	Attribute string
+}
+
+// String formats the part as label.<index> or attribute.<name>.
+func (k KeyPart) String() string {
+	if k.Attribute != "" {
+		return "attribute." + k.Attribute
+	}
+	return "label." + strconv.Itoa(k.Label)
+}
+
+// ParseKeyPart parses label.<index> or attribute.<name>.
+func ParseKeyPart(value string) (KeyPart, error) {
+	kind, arg, ok := strings.Cut(value, ".")
+	switch {
+	case ok && kind == "label":
+		if index, err := strconv.Atoi(arg); err == nil && index >= 0 {
+			return KeyPart{Label: index}, nil
+		}
+	case ok && kind == "attribute" && hclsyntax.ValidIdentifier(arg):
+		return KeyPart{Attribute: arg}, nil
+	}
+	return KeyPart{}, fmt.Errorf("invalid sort key '%s': must be label.<index> or attribute.<name>", value)
+}
+
+// BlockRule says how the blocks at a path and their bodies are sorted.
+type BlockRule struct {
+	// Path names the block types from the top level down, such as job > group > task.
+	// An empty path stands for the top level of the file.
+	Path []string
+	// SortBy is the key the blocks at Path are sorted by. They are not reordered when
+	// it is empty. Blocks that lack a part of the key stay where they are.
+	SortBy []KeyPart
+	// InPlace sorts the blocks at Path within the positions they already take, even
+	// when the profile moves sorted blocks after the others.
+	InPlace bool
+	// SortAttributes sorts the attributes in the bodies of the blocks at Path by name.
+	SortAttributes bool
+}
+
+// ParseBlockPath parses a block path such as "job > group > task". An empty value is the
+// top level of a file.
+func ParseBlockPath(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var path []string
+	for _, part := range strings.Split(value, ">") {
+		part = strings.TrimSpace(part)
+		if !hclsyntax.ValidIdentifier(part) {
+			return nil, fmt.Errorf("invalid block path '%s': parts must be block types separated by '>'", value)
+		}
+		path = append(path, part)
+	}
+	return path, nil
+}
+
+// Profile describes what is sorted in one kind of HCL file.
+type Profile struct {
+	Name string
+	// Files are the patterns, matched against the base name, of the files that use the
+	// profile.
+	Files []string
+	// InPlace sorts the blocks of each type within the positions that type already
+	// takes, so other blocks stay where they are. Otherwise the sorted blocks of a body
+	// are ordered together and moved after all other blocks.
+	InPlace bool
+	Rules   []BlockRule
+}
+
+// TerraformProfile returns the profile of Terraform and OpenTofu files, which sorts the
+// allowed block types by their first label. The key_provider and method blocks of
+// OpenTofu's state encryption are sorted by type and name within their positions.
+func TerraformProfile(allowedBlocks map[string]bool) Profile {
+	typeAndName := []KeyPart{{Label: 0}, {Label: 1}}
+	profile := Profile{
+		Name: ProfileTerraform,
+		Rules: []BlockRule{
+			{Path: []string{"terraform", "required_providers"}, SortAttributes: true},
+			{Path: []string{"terraform", "encryption", "key_provider"}, SortBy: typeAndName, InPlace: true},
+			{Path: []string{"terraform", "encryption", "method"}, SortBy: typeAndName, InPlace: true},
+			{Path: []string{"locals"}, SortAttributes: true},
+		},
+	}
+	blockTypes := make([]string, 0, len(allowedBlocks))
+	for blockType, allowed := range allowedBlocks {
+		if allowed {
+			blockTypes = append(blockTypes, blockType)
+		}
+	}
+	sort.Strings(blockTypes)
+	for _, blockType := range blockTypes {
+		profile.Rules = append(profile.Rules, BlockRule{Path: []string{blockType}, SortBy: []KeyPart{{Label: 0}}})
+	}
+	return profile
+}
+
+// PackerProfile returns the profile of Packer templates. Variables are sorted by name and
+// sources by type and name, each within their own positions; build blocks are never moved
+// because the order of their provisioners matters.
+func PackerProfile() Profile {
+	return Profile{
+		Name:    ProfilePacker,
+		Files:   []string{"*.pkr.hcl"},
+		InPlace: true,
+		Rules: []BlockRule{
+			{Path: []string{"variable"}, SortBy: []KeyPart{{Label: 0}}},
+			{Path: []string{"source"}, SortBy: []KeyPart{{Label: 0}, {Label: 1}}},
+			{Path: []string{"packer", "required_plugins"}, SortAttributes: true},
+			{Path: []string{"locals"}, SortAttributes: true},
+		},
+	}
+}
+
+// PackerVarsProfile returns the profile of Packer variable definition files, whose
+// values are sorted by name.
+func PackerVarsProfile() Profile {
+	return Profile{
+		Name:  ProfilePackerVars,
+		Files: []string{"*.pkrvars.hcl"},
+		Rules: []BlockRule{{SortAttributes: true}},
+	}
+}
+
+// NomadProfile returns the profile of Nomad job specifications. Variables, groups and
+// tasks are sorted by name within their positions, and environment and metadata entries
+// by key.
+func NomadProfile() Profile {
+	profile := Profile{
+		Name:    ProfileNomad,
+		Files:   []string{"*.nomad", "*.nomad.hcl"},
+		InPlace: true,
+		Rules: []BlockRule{
+			{Path: []string{"variable"}, SortBy: []KeyPart{{Label: 0}}},
+			{Path: []string{"locals"}, SortAttributes: true},
+			{Path: []string{"job", "group"}, SortBy: []KeyPart{{Label: 0}}},
+			{Path: []string{"job", "group", "task"}, SortBy: []KeyPart{{Label: 0}}},
+			{Path: []string{"job", "group", "task", "env"}, SortAttributes: true},
+		},
+	}
+	for _, path := range [][]string{{"job"}, {"job", "group"}, {"job", "group", "task"}} {
+		profile.Rules = append(profile.Rules, BlockRule{Path: append(path, "meta"), SortAttributes: true})
+	}
+	return profile
+}
+
+// TestProfile returns the profile of Terraform and OpenTofu test files. Run blocks are
+// never reordered because they execute in file order; mock providers are sorted by name
+// within their positions, and the values of variables blocks by name.
+func TestProfile() Profile {
+	return Profile{
+		Name:    ProfileTest,
+		Files:   []string{"*.tftest.hcl", "*.tofutest.hcl"},
+		InPlace: true,
+		Rules: []BlockRule{
+			{Path: []string{"mock_provider"}, SortBy: []KeyPart{{Label: 0}}},
+			{Path: []string{"variables"}, SortAttributes: true},
+			{Path: []string{"run", "variables"}, SortAttributes: true},
+		},
+	}
+}
+
+// Profile returns the profile for the file with the given name. The configured profiles
+// are tried first, then the built-in Packer, Nomad and test profiles. Files that no profile
+// matches use the Terraform profile: the configured one or, without it, the built-in
+// one with the allowed blocks.
+func (i *Ingestor) Profile(filename string) Profile {
+	base := filepath.Base(filename)
+	fallback := TerraformProfile(i.AllowedBlocks)
+	for _, profile := range i.Profiles {
+		switch {
+		case profile.Name == ProfileTerraform:
+			fallback = profile
+		case profile.matches(base):
+			return profile
+		}
+	}
+	for _, profile := range []Profile{PackerProfile(), PackerVarsProfile(), NomadProfile(), TestProfile()} {
+		if !slices.ContainsFunc(i.Profiles, func(p Profile) bool { return p.Name == profile.Name }) &&
+			profile.matches(base) {
+			return profile
+		}
+	}
+	return fallback
+}
+
+// BuiltinProfile returns the built-in profile with the given name, with the default
+// Terraform blocks for the Terraform profile.
+func BuiltinProfile(name string) (Profile, bool) {
+	switch name {
+	case ProfileTerraform:
+		return TerraformProfile(NewIngestor().AllowedBlocks), true
+	case ProfilePacker:
+		return PackerProfile(), true
+	case ProfilePackerVars:
+		return PackerVarsProfile(), true
+	case ProfileNomad:
+		return NomadProfile(), true
+	case ProfileTest:
+		return TestProfile(), true
+	default:
+		return Profile{}, false
+	}
+}
+
+// Extensions returns the file extensions, without the dot, that the file patterns of the
+// profile name literally.
+func (p Profile) Extensions() []string {
+	var extensions []string
+	for _, pattern := range p.Files {
+		ext := strings.TrimPrefix(filepath.Ext(pattern), ".")
+		if ext != "" && !strings.ContainsAny(ext, "*?[\\") {
+			extensions = append(extensions, ext)
+		}
+	}
+	return extensions
+}
+
+// matches reports whether the profile applies to the file with the given base name.
+func (p Profile) matches(base string) bool {
+	for _, pattern := range p.Files {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rule returns the first rule for path. Later rules for the same path are ignored, so a
+// rule can override a built-in one.
+func (p Profile) rule(path []string) (BlockRule, bool) {
+	for _, rule := range p.Rules {
+		if slices.Equal(rule.Path, path) {
+			return rule, true
+		}
+	}
+	return BlockRule{}, false
+}
+
+// sortsAttributes reports whether the attributes of the bodies at path are sorted.
+func (p Profile) sortsAttributes(path []string) bool {
+	rule, ok := p.rule(path)
+	return ok && rule.SortAttributes
+}
+
+// reaches reports whether a rule applies to path or to a block nested below it.
+func (p Profile) reaches(path []string) bool {
+	for _, rule := range p.Rules {
+		if len(rule.Path) >= len(path) && slices.Equal(rule.Path[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortableBlock is what the profile needs to know about a block to order it.
+type sortableBlock struct {
+	blockType string
+	labels    []string
+	// attribute returns the value of an attribute of the block as text.
+	attribute func(name string) (string, bool)
+}
+
+// order returns the order the profile gives to the blocks of a body at parent: order[i]
+// is the index of the block that ends up in position i.
+func (p Profile) order(parent []string, blocks []sortableBlock) []int {
+	keys := make([][]string, len(blocks))
+	// kept holds the blocks that are not moved after the others, in source order.
+	var kept, moved []int
+	byType := map[string][]int{}
+	for i, block := range blocks {
+		rule, key, ok := p.sortKey(parent, block)
+		keys[i] = key
+		switch {
+		case !ok:
+			kept = append(kept, i)
+		case p.InPlace || rule.InPlace:
+			byType[block.blockType] = append(byType[block.blockType], len(kept))
+			kept = append(kept, i)
+		default:
+			moved = append(moved, i)
+		}
+	}
+	less := func(a, b int) bool { return slices.Compare(keys[a], keys[b]) < 0 }
+
+	order := slices.Clone(kept)
+	for _, slots := range byType {
+		indexes := make([]int, len(slots))
+		for n, slot := range slots {
+			indexes[n] = kept[slot]
+		}
+		sort.SliceStable(indexes, func(a, b int) bool { return less(indexes[a], indexes[b]) })
+		for n, slot := range slots {
+			order[slot] = indexes[n]
+		}
+	}
+	sort.SliceStable(moved, func(a, b int) bool { return less(moved[a], moved[b]) })
+	return append(order, moved...)
+}
+
+// sortKey returns the rule and the key a block of a body at parent is sorted by, and
+// whether the profile sorts it at all.
+func (p Profile) sortKey(parent []string, block sortableBlock) (BlockRule, []string, bool) {
+	rule, ok := p.rule(append(slices.Clip(parent), block.blockType))
+	if !ok || len(rule.SortBy) == 0 {
+		return rule, nil, false
+	}
+	key := make([]string, 0, len(rule.SortBy))
+	for _, part := range rule.SortBy {
+		switch {
+		case part.Attribute != "":
+			value, found := block.attribute(part.Attribute)
+			if !found {
+				return rule, nil, false
+			}
+			key = append(key, value)
+		case part.Label < len(block.labels):
+			key = append(key, block.labels[part.Label])
+		default:
+			return rule, nil, false
+		}
+	}
+	return rule, key, true
+}
+
+// keyText turns the source text of an expression into a sort key, without the quotes
+// of a plain string.
+func keyText(text string) string {
+	text = strings.TrimSpace(text)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		return unquoted
+	}
+	return text
+}
+
+// syntaxOrder returns the order the profile gives to the blocks of a body at parent.
+func (p Profile) syntaxOrder(src []byte, parent []string, blocks hclsyntax.Blocks) []int {
+	views := make([]sortableBlock, len(blocks))
+	for i, block := range blocks {
+		views[i] = sortableBlock{
+			blockType: block.Type,
+			labels:    block.Labels,
+			attribute: func(name string) (string, bool) {
+				attr, ok := block.Body.Attributes[name]
+				if !ok {
+					return "", false
+				}
+				rng := attr.Expr.Range()
+				return keyText(string(src[rng.Start.Byte:rng.End.Byte])), true
+			},
+		}
+	}
+	return p.order(parent, views)
+}
+
+// writeOrder returns the order the profile gives to the top-level blocks of a file.
+func (p Profile) writeOrder(blocks []*hclwrite.Block) []int {
+	views := make([]sortableBlock, len(blocks))
+	for i, block := range blocks {
+		views[i] = sortableBlock{
+			blockType: block.Type(),
+			labels:    block.Labels(),
+			attribute: func(name string) (string, bool) {
+				attr := block.Body().GetAttribute(name)
+				if attr == nil {
+					return "", false
+				}
+				return keyText(string(attr.Expr().BuildTokens(nil).Bytes())), true
+			},
+		}
+	}
+	return p.order(nil, views)
+}
+
+// walkSyntaxBodies calls fn for body, at path, and for the bodies of the blocks nested
+// in it that a rule of the profile reaches.
+func (p Profile) walkSyntaxBodies(body *hclsyntax.Body, path []string, fn func(path []string, body *hclsyntax.Body)) {
+	fn(path, body)
+	for _, block := range body.Blocks {
+		if nested := append(slices.Clip(path), block.Type); p.reaches(nested) {
+			p.walkSyntaxBodies(block.Body, nested, fn)
+		}
+	}
+}
+
+// syntaxAttributeBodies returns the nested bodies of body whose attributes the profile
+// sorts by name.
+func (p Profile) syntaxAttributeBodies(body *hclsyntax.Body) []*hclsyntax.Body {
+	var targets []*hclsyntax.Body
+	p.walkSyntaxBodies(body, nil, func(path []string, nested *hclsyntax.Body) {
+		if len(path) > 0 && p.sortsAttributes(path) {
+			targets = append(targets, nested)
+		}
+	})
+	return targets
+}
+
+// writeAttributeBodies returns the nested bodies of body whose attributes the profile
+// sorts by name.
+func (p Profile) writeAttributeBodies(body *hclwrite.Body) []*hclwrite.Body {
+	var targets []*hclwrite.Body
+	var walk func(path []string, body *hclwrite.Body)
+	walk = func(path []string, body *hclwrite.Body) {
+		if len(path) > 0 && p.sortsAttributes(path) {
+			targets = append(targets, body)
+		}
+		for _, block := range body.Blocks() {
+			if nested := append(slices.Clip(path), block.Type()); p.reaches(nested) {
+				walk(nested, block.Body())
+			}
+		}
+	}
+	walk(nil, body)
+	return targets
+}
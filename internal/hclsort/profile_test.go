@@ -0,0 +1,247 @@
+package hclsort_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPackerProfile(t *testing.T) {
+	t.Parallel()
+
+	template := "packer {\n  required_plugins {\n    docker = {\n      source = \"github.com/hashicorp/docker\"\n    }\n" +
+		"    amazon = {\n      source = \"github.com/hashicorp/amazon\"\n    }\n  }\n}\n\n" +
+		"variable \"region\" {}\n\nvariable \"ami\" {}\n\n" +
+		"source \"docker\" \"ubuntu\" {}\n\nsource \"amazon-ebs\" \"web\" {}\n\nsource \"amazon-ebs\" \"base\" {}\n\n" +
+		"build {\n  provisioner \"shell\" {}\n\n  provisioner \"file\" {}\n}\n"
+	wantTemplate := "packer {\n  required_plugins {\n    amazon = {\n      source = \"github.com/hashicorp/amazon\"\n    }\n" +
+		"    docker = {\n      source = \"github.com/hashicorp/docker\"\n    }\n  }\n}\n\n" +
+		"variable \"ami\" {}\n\nvariable \"region\" {}\n\n" +
+		"source \"amazon-ebs\" \"base\" {}\n\nsource \"amazon-ebs\" \"web\" {}\n\nsource \"docker\" \"ubuntu\" {}\n\n" +
+		"build {\n  provisioner \"shell\" {}\n\n  provisioner \"file\" {}\n}\n"
+
+	tests := []struct {
+		name     string
+		filename string
+		input    string
+		want     string
+	}{
+		{name: "Template", filename: "image.pkr.hcl", input: template, want: wantTemplate},
+		{
+			name:     "Variable definitions",
+			filename: "image.pkrvars.hcl",
+			input:    "region = \"eu\"\n# The image.\nimages = 2\n",
+			want:     "# The image.\nimages = 2\nregion = \"eu\"\n",
+		},
+		{
+			name:     "Plain HCL keeps the Terraform profile",
+			filename: "image.hcl",
+			input:    "source \"b\" \"x\" {}\n\nvariable \"b\" {}\n\nvariable \"a\" {}\n",
+			want:     "source \"b\" \"x\" {}\n\nvariable \"a\" {}\n\nvariable \"b\" {}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		for _, noFormat := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/NoFormat=%v", tc.name, noFormat), func(t *testing.T) {
+				t.Parallel()
+
+				ingestor := hclsort.NewIngestor()
+				ingestor.NoFormat = noFormat
+				got, err := ingestor.Sort([]byte(tc.input), tc.filename)
+				if err != nil {
+					t.Fatalf("Sort failed: %v", err)
+				}
+				if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+					t.Errorf("unexpected output (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+
+	items, err := hclsort.NewIngestor().FindUnsorted([]byte(template), "image.pkr.hcl")
+	if err != nil {
+		t.Fatalf("FindUnsorted failed: %v", err)
+	}
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Description)
+	}
+	want := []string{
+		`variable "ami"`, `variable "region"`,
+		`source "amazon-ebs" "base"`, `source "docker" "ubuntu"`,
+		`"amazon"`, `"docker"`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected unsorted items (-want +got):\n%s", diff)
+	}
+}
+
+func TestNomadProfile(t *testing.T) {
+	t.Parallel()
+
+	input := "job \"web\" {\n  meta {\n    team  = \"a\"\n    owner = \"b\"\n  }\n\n" +
+		"  group \"frontend\" {\n    count = 2\n\n    # The server.\n    task \"server\" {\n      env {\n        ZED   = \"1\"\n" +
+		"        ALPHA = \"2\"\n      }\n    }\n\n    task \"proxy\" {}\n  }\n\n  group \"backend\" {}\n}\n"
+	want := "job \"web\" {\n  meta {\n    owner = \"b\"\n    team  = \"a\"\n  }\n\n" +
+		"  group \"backend\" {}\n\n  group \"frontend\" {\n    count = 2\n\n    task \"proxy\" {}\n\n" +
+		"    # The server.\n    task \"server\" {\n      env {\n        ALPHA = \"2\"\n        ZED   = \"1\"\n" +
+		"      }\n    }\n  }\n}\n"
+
+	for _, noFormat := range []bool{false, true} {
+		ingestor := hclsort.NewIngestor()
+		ingestor.NoFormat = noFormat
+		got, err := ingestor.Sort([]byte(input), "web.nomad.hcl")
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("unexpected output with NoFormat=%v (-want +got):\n%s", noFormat, diff)
+		}
+	}
+}
+
+func TestConfiguredProfiles(t *testing.T) {
+	t.Parallel()
+
+	ingestor := hclsort.NewIngestor()
+	ingestor.Profiles = []hclsort.Profile{
+		{
+			Name:  "consul",
+			Files: []string{"*.consul.hcl"},
+			Rules: []hclsort.BlockRule{{
+				Path:           []string{"service"},
+				SortBy:         []hclsort.KeyPart{{Attribute: "name"}},
+				SortAttributes: true,
+			}},
+		},
+		{
+			Name:  hclsort.ProfileTerraform,
+			Rules: []hclsort.BlockRule{{Path: []string{"module"}, SortBy: []hclsort.KeyPart{{Label: 0}}}},
+		},
+	}
+
+	tests := []struct {
+		filename string
+		input    string
+		want     string
+	}{
+		{
+			filename: "web.consul.hcl",
+			input:    "service {\n  port = 80\n  name = \"web\"\n}\n\nservice {\n  name = \"api\"\n}\n\nservice {}\n",
+			want:     "service {}\n\nservice {\n  name = \"api\"\n}\n\nservice {\n  name = \"web\"\n  port = 80\n}\n",
+		},
+		{
+			filename: "main.tf",
+			input:    "module \"b\" {}\n\nvariable \"b\" {}\n\nvariable \"a\" {}\n\nmodule \"a\" {}\n",
+			want:     "variable \"b\" {}\n\nvariable \"a\" {}\n\nmodule \"a\" {}\n\nmodule \"b\" {}\n",
+		},
+	}
+	for _, tc := range tests {
+		got, err := ingestor.Sort([]byte(tc.input), tc.filename)
+		if err != nil {
+			t.Fatalf("Sort(%s) failed: %v", tc.filename, err)
+		}
+		if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+			t.Errorf("unexpected output for %s (-want +got):\n%s", tc.filename, diff)
+		}
+	}
+
+	if got := ingestor.Profile("image.pkr.hcl").Name; got != hclsort.ProfilePacker {
+		t.Errorf("expected the built-in packer profile, got %q", got)
+	}
+}
+
+func TestParseBlockRules(t *testing.T) {
+	t.Parallel()
+
+	path, err := hclsort.ParseBlockPath("job > group>task")
+	if err != nil {
+		t.Fatalf("ParseBlockPath failed: %v", err)
+	}
+	if diff := cmp.Diff([]string{"job", "group", "task"}, path); diff != "" {
+		t.Errorf("unexpected path (-want +got):\n%s", diff)
+	}
+	if _, err = hclsort.ParseBlockPath("job >> task"); err == nil {
+		t.Error("expected an error for an empty path part")
+	}
+
+	for value, want := range map[string]hclsort.KeyPart{
+		"label.1":        {Label: 1},
+		"attribute.name": {Attribute: "name"},
+	} {
+		part, partErr := hclsort.ParseKeyPart(value)
+		if partErr != nil {
+			t.Fatalf("ParseKeyPart(%s) failed: %v", value, partErr)
+		}
+		if part != want || part.String() != value {
+			t.Errorf("ParseKeyPart(%s) = %+v", value, part)
+		}
+	}
+	for _, value := range []string{"label", "label.-1", "attribute.", "name"} {
+		if _, err = hclsort.ParseKeyPart(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+func TestOpenTofuEncryption(t *testing.T) {
+	t.Parallel()
+
+	input := "terraform {\n  encryption {\n    method \"aes_gcm\" \"new\" {}\n\n    key_provider \"pbkdf2\" \"b\" {}\n\n" +
+		"    key_provider \"pbkdf2\" \"a\" {}\n\n    method \"aes_gcm\" \"old\" {}\n\n    state {}\n  }\n}\n"
+	want := "terraform {\n  encryption {\n    method \"aes_gcm\" \"new\" {}\n\n    key_provider \"pbkdf2\" \"a\" {}\n\n" +
+		"    key_provider \"pbkdf2\" \"b\" {}\n\n    method \"aes_gcm\" \"old\" {}\n\n    state {}\n  }\n}\n"
+
+	for _, noFormat := range []bool{false, true} {
+		ingestor := hclsort.NewIngestor()
+		ingestor.NoFormat = noFormat
+		got, err := ingestor.Sort([]byte(input), "main.tofu")
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("unexpected output with NoFormat=%v (-want +got):\n%s", noFormat, diff)
+		}
+	}
+
+	// Without formatting, reordered blocks keep the separators they had.
+	adjacent := "terraform {\n  encryption {\n    key_provider \"pbkdf2\" \"b\" {}\n" +
+		"    key_provider \"pbkdf2\" \"a\" {}\n    method \"aes_gcm\" \"new\" {}\n  }\n}\n"
+	wantAdjacent := "terraform {\n  encryption {\n    key_provider \"pbkdf2\" \"a\" {}\n" +
+		"    key_provider \"pbkdf2\" \"b\" {}\n    method \"aes_gcm\" \"new\" {}\n  }\n}\n"
+	ingestor := hclsort.NewIngestor()
+	ingestor.NoFormat = true
+	got, err := ingestor.Sort([]byte(adjacent), "main.tofu")
+	if err != nil {
+		t.Fatalf("Sort failed: %v", err)
+	}
+	if diff := cmp.Diff(wantAdjacent, string(got)); diff != "" {
+		t.Errorf("unexpected output for adjacent blocks (-want +got):\n%s", diff)
+	}
+}
+
+func TestTestProfile(t *testing.T) {
+	t.Parallel()
+
+	input := "variables {\n  region = \"eu\"\n  name   = \"x\"\n}\n\nmock_provider \"google\" {}\n\n" +
+		"run \"second\" {\n  variables {\n    zeta  = 1\n    alpha = 2\n  }\n}\n\n" +
+		"mock_provider \"aws\" {}\n\nrun \"first\" {}\n"
+	want := "variables {\n  name   = \"x\"\n  region = \"eu\"\n}\n\nmock_provider \"aws\" {}\n\n" +
+		"run \"second\" {\n  variables {\n    alpha = 2\n    zeta  = 1\n  }\n}\n\n" +
+		"mock_provider \"google\" {}\n\nrun \"first\" {}\n"
+
+	for _, noFormat := range []bool{false, true} {
+		ingestor := hclsort.NewIngestor()
+		ingestor.NoFormat = noFormat
+		got, err := ingestor.Sort([]byte(input), "main.tftest.hcl")
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("unexpected output with NoFormat=%v (-want +got):\n%s", noFormat, diff)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package hclsort
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// ProviderConflict is a provider requirement key that is declared with two different
+// values in one module.
+type ProviderConflict struct {
+	Provider string
+	Key      string
+	// First and Second are the positions and source text of the two values.
+	First       hcl.Range
+	FirstValue  string
+	Second      hcl.Range
+	SecondValue string
+}
+
+// String formats the conflict with both of its positions.
+func (c ProviderConflict) String() string {
+	return fmt.Sprintf("%s: conflicting %s %s for provider %q, first declared as %s at %s",
+		formatPos(c.Second), c.Key, c.SecondValue, c.Provider, c.FirstValue, formatPos(c.First))
+}
+
+// ProviderConflictError reports the provider requirements of a module that disagree.
+type ProviderConflictError struct {
+	Conflicts []ProviderConflict
+}
+
+// Error implements the error interface.
+func (e *ProviderConflictError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		lines[i] = conflict.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// requirementValue is the source text of one key of a provider requirement.
+type requirementValue struct {
+	text string
+	rng  hcl.Range
+}
+
+// providerRequirement is the merged requirement of one provider.
+type providerRequirement struct {
+	name string
+	// comments are the comment lines found above the provider's declarations.
+	comments []string
+	values   map[string]requirementValue
+	// aliases are the elements of configuration_aliases, without repeats.
+	aliases []string
+}
+
+// MergeProviders plans collecting the required_providers entries of the given files,
+// which belong to one module, into a single required_providers block in the first
+// terraform block of target. The file is created if needed, and terraform blocks left
+// empty elsewhere are removed. Entries of the same provider are combined, legacy string
+// entries become objects and the keys of each object are ordered source, version,
+// configuration_aliases. A *ProviderConflictError is returned, and nothing is planned,
+// when two entries of a provider set a key to different values. The changes are
+// returned in path order.
+func (i *Ingestor) MergeProviders(paths []string, target string) ([]FileChange, error) {
+	files := map[string]*splitFile{}
+	modFiles := make([]*ModuleFile, 0, len(paths))
+	for _, path := range paths {
+		original, err := ReadFileBytes(i.fileSystem(), path)
+		if err != nil {
+			return nil, err
+		}
+		file, err := NewModuleFile(path, original)
+		if err != nil {
+			return nil, err
+		}
+		modFiles = append(modFiles, file)
+		files[path] = &splitFile{original: original, exists: true, style: DetectTextStyle(original), content: file.Src}
+	}
+
+	requirements, conflicts := collectRequirements(modFiles)
+	if len(conflicts) > 0 {
+		return nil, &ProviderConflictError{Conflicts: conflicts}
+	}
+	if len(requirements) == 0 {
+		return nil, nil
+	}
+
+	for _, file := range modFiles {
+		if rest, removed := takeRequiredProviders(file.Src, file.Body, file.Path == target); removed {
+			files[file.Path].content = rest
+			files[file.Path].touched = true
+		}
+	}
+
+	f, ok := files[target]
+	if !ok {
+		src, err := i.fileSystem().ReadFile(target)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		style := DetectTextStyle(src)
+		if err != nil {
+			style = files[modFiles[0].Path].style
+		}
+		f = &splitFile{original: src, exists: err == nil, style: style, content: style.Normalize(src)}
+		files[target] = f
+	}
+
+	content, err := insertRequiredProviders(f.content, target, renderRequiredProviders(requirements))
+	if err != nil {
+		return nil, err
+	}
+	f.content = content
+	f.touched = true
+
+	return i.splitChanges(files)
+}
+
+// collectRequirements merges the required_providers entries of files by provider name,
+// in name order. It also returns the keys that were set to different values.
+func collectRequirements(files []*ModuleFile) ([]*providerRequirement, []ProviderConflict) {
+	byName := map[string]*providerRequirement{}
+	var conflicts []ProviderConflict
+	for _, file := range files {
+		for _, body := range requiredProvidersBodies(file.Body) {
+			floor := body.SrcRange.Start.Byte + 1
+			for _, attr := range attributesInSourceOrder(body) {
+				item := itemSpan(file.Src, attr.SrcRange, floor)
+				floor = item.end
+
+				req, ok := byName[attr.Name]
+				if !ok {
+					req = &providerRequirement{name: attr.Name, values: map[string]requirementValue{}}
+					byName[attr.Name] = req
+				}
+				comments := strings.TrimSpace(string(file.Src[item.start:lineStartAt(file.Src, attr.SrcRange.Start.Byte)]))
+				if comments != "" && !slices.Contains(req.comments, comments) {
+					req.comments = append(req.comments, comments)
+				}
+				conflicts = append(conflicts, req.add(file.Src, attr)...)
+			}
+		}
+	}
+
+	requirements := make([]*providerRequirement, 0, len(byName))
+	for _, req := range byName {
+		requirements = append(requirements, req)
+	}
+	sort.Slice(requirements, func(a, b int) bool { return requirements[a].name < requirements[b].name })
+	return requirements, conflicts
+}
+
+// requiredProvidersBodies returns the bodies of the required_providers blocks nested in
+// the terraform blocks of body.
+func requiredProvidersBodies(body *hclsyntax.Body) []*hclsyntax.Body {
+	var bodies []*hclsyntax.Body
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		for _, nested := range block.Body.Blocks {
+			if nested.Type == "required_providers" {
+				bodies = append(bodies, nested.Body)
+			}
+		}
+	}
+	return bodies
+}
+
+// add merges the entry attr into the requirement and returns the keys it conflicts on.
+// A string entry is the legacy form of a version constraint.
+func (r *providerRequirement) add(src []byte, attr *hclsyntax.Attribute) []ProviderConflict {
+	object, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return r.set("version", exprText(src, attr.Expr), attr.Expr.Range())
+	}
+
+	var conflicts []ProviderConflict
+	for _, item := range object.Items {
+		key := strings.Trim(exprText(src, item.KeyExpr), `"`)
+		if tuple, isTuple := item.ValueExpr.(*hclsyntax.TupleConsExpr); isTuple && key == "configuration_aliases" {
+			for _, alias := range tuple.Exprs {
+				if text := exprText(src, alias); !slices.Contains(r.aliases, text) {
+					r.aliases = append(r.aliases, text)
+				}
+			}
+			continue
+		}
+		conflicts = append(conflicts, r.set(key, exprText(src, item.ValueExpr), item.ValueExpr.Range())...)
+	}
+	return conflicts
+}
+
+// set records the value of key, or reports a conflict with an earlier, different value.
+func (r *providerRequirement) set(key, text string, rng hcl.Range) []ProviderConflict {
+	prev, ok := r.values[key]
+	if !ok {
+		r.values[key] = requirementValue{text: text, rng: rng}
+		return nil
+	}
+	if prev.text == text {
+		return nil
+	}
+	return []ProviderConflict{{
+		Provider:    r.name,
+		Key:         key,
+		First:       prev.rng,
+		FirstValue:  prev.text,
+		Second:      rng,
+		SecondValue: text,
+	}}
+}
+
+// render returns the requirement as an entry of a required_providers block.
+func (r *providerRequirement) render() string {
+	values := map[string]string{}
+	for key, value := range r.values {
+		values[key] = value.text
+	}
+	if len(r.aliases) > 0 {
+		values["configuration_aliases"] = "[" + strings.Join(r.aliases, ", ") + "]"
+	}
+
+	keys := make([]string, 0, len(values))
+	width := 0
+	for key := range values {
+		keys = append(keys, key)
+		width = max(width, len(key))
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		rankA, rankB := requirementKeyRank(keys[a]), requirementKeyRank(keys[b])
+		if rankA != rankB {
+			return rankA < rankB
+		}
+		return keys[a] < keys[b]
+	})
+
+	var buf strings.Builder
+	for _, comment := range r.comments {
+		for _, line := range strings.Split(comment, "\n") {
+			buf.WriteString(strings.TrimSpace(line) + "\n")
+		}
+	}
+	fmt.Fprintf(&buf, "%s = {\n", r.name)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "  %-*s = %s\n", width, key, values[key])
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// requirementKeyRank orders the keys of a provider requirement: source, version and
+// configuration_aliases come first, other keys follow.
+func requirementKeyRank(key string) int {
+	known := []string{"source", "version", "configuration_aliases"}
+	if rank := slices.Index(known, key); rank >= 0 {
+		return rank
+	}
+	return len(known)
+}
+
+// renderRequiredProviders returns a required_providers block holding requirements,
+// indented for a terraform block.
+func renderRequiredProviders(requirements []*providerRequirement) []byte {
+	var entries bytes.Buffer
+	for _, req := range requirements {
+		entries.WriteString(req.render())
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("required_providers {\n")
+	buf.Write(indentLines(entries.Bytes()))
+	buf.WriteString("}\n")
+	return indentLines(buf.Bytes())
+}
+
+// takeRequiredProviders removes the required_providers blocks of src, along with the
+// terraform blocks left empty by it, except for the first terraform block when
+// keepFirst is set. It reports whether anything was removed.
+func takeRequiredProviders(src []byte, body *hclsyntax.Body, keepFirst bool) ([]byte, bool) {
+	var removed []span
+	floor := 0
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+		if block.Type != "terraform" {
+			continue
+		}
+
+		var nested []span
+		innerFloor := block.OpenBraceRange.End.Byte
+		for _, child := range block.Body.Blocks {
+			childItem := itemSpan(src, child.Range(), innerFloor)
+			innerFloor = childItem.end
+			if child.Type == "required_providers" {
+				nested = append(nested, childItem)
+			}
+		}
+
+		inner := replaceSpans(src[:block.CloseBraceRange.Start.Byte], nested, make([][]byte, len(nested)))
+		emptied := len(bytes.TrimSpace(inner[block.OpenBraceRange.End.Byte:])) == 0
+		if emptied && !keepFirst {
+			removed = append(removed, withBlankLines(src, item))
+		} else {
+			for _, child := range nested {
+				child = withBlankLines(src, child)
+				if len(bytes.TrimSpace(src[child.end:block.CloseBraceRange.Start.Byte])) == 0 {
+					// The last item of the body also takes the blank lines above it.
+					for child.start > 1 && src[child.start-1] == '\n' && src[child.start-2] == '\n' {
+						child.start--
+					}
+				}
+				removed = append(removed, child)
+			}
+		}
+		keepFirst = false
+	}
+
+	if len(removed) == 0 {
+		return src, false
+	}
+	out := replaceSpans(src, removed, make([][]byte, len(removed)))
+	if bytes.HasSuffix(src, []byte("\n")) {
+		out = append(bytes.TrimRight(out, "\n"), '\n')
+	}
+	return out, true
+}
+
+// insertRequiredProviders adds block at the end of the first terraform block of src,
+// which must use LF line endings, or appends a new terraform block holding it.
+func insertRequiredProviders(src []byte, filename string, block []byte) ([]byte, error) {
+	body, err := parseSyntaxBody(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range body.Blocks {
+		if existing.Type != "terraform" {
+			continue
+		}
+		openBrace, closeBrace := existing.OpenBraceRange.End.Byte, existing.CloseBraceRange.Start.Byte
+		region := span{start: lineStartAt(src, closeBrace), end: lineStartAt(src, closeBrace)}
+		var text bytes.Buffer
+		switch inner := bytes.TrimSpace(src[openBrace:closeBrace]); {
+		case region.start <= existing.OpenBraceRange.Start.Byte:
+			// A single-line block is spread over several lines.
+			region = span{start: openBrace, end: closeBrace}
+			text.WriteByte('\n')
+			if len(inner) > 0 {
+				fmt.Fprintf(&text, "  %s\n\n", inner)
+			}
+		case len(bytes.TrimSpace(src[region.start:closeBrace])) > 0:
+			region = span{start: closeBrace, end: closeBrace}
+			text.WriteString("\n\n")
+		case len(inner) > 0:
+			text.WriteByte('\n')
+		}
+		text.Write(block)
+		return replaceSpans(src, []span{region}, [][]byte{text.Bytes()}), nil
+	}
+
+	var buf bytes.Buffer
+	if trimmed := trimTrailingBlankLines(src); len(trimmed) > 0 {
+		buf.Write(trimmed)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("terraform {\n")
+	buf.Write(block)
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// withBlankLines extends item over the blank lines that follow it.
+func withBlankLines(src []byte, item span) span {
+	for item.end < len(src) && src[item.end] == '\n' {
+		item.end++
+	}
+	return item
+}
+
+// exprText returns the source text of expr.
+func exprText(src []byte, expr hclsyntax.Expression) string {
+	rng := expr.Range()
+	return string(src[rng.Start.Byte:rng.End.Byte])
+}
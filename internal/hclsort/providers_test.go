@@ -0,0 +1,95 @@
+package hclsort_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeProviders(t *testing.T) {
+	t.Parallel()
+
+	mainTF := "terraform {\n  required_version = \">= 1.5\"\n\n  required_providers {\n" +
+		"    aws = {\n      version = \">= 5.0\"\n      source  = \"hashicorp/aws\"\n    }\n  }\n}\n\n" +
+		"resource \"null_resource\" \"x\" {}\n"
+	extraTF := "terraform {\n  required_providers {\n    # randomness\n    random = \"~> 3.0\"\n" +
+		"    aws = {\n      configuration_aliases = [aws.east]\n    }\n  }\n}\n"
+
+	ingestor := newMemIngestor(map[string]string{"main.tf": mainTF, "extra.tf": extraTF})
+
+	changes, err := ingestor.MergeProviders([]string{"extra.tf", "main.tf"}, "versions.tf")
+	if err != nil {
+		t.Fatalf("MergeProviders failed: %v", err)
+	}
+	want := []hclsort.FileChange{
+		{Path: "extra.tf", Before: []byte(extraTF)},
+		{
+			Path:   "main.tf",
+			Before: []byte(mainTF),
+			After:  []byte("terraform {\n  required_version = \">= 1.5\"\n}\n\nresource \"null_resource\" \"x\" {}\n"),
+		},
+		{
+			Path: "versions.tf",
+			After: []byte("terraform {\n  required_providers {\n" +
+				"    aws = {\n      source                = \"hashicorp/aws\"\n      version               = \">= 5.0\"\n" +
+				"      configuration_aliases = [aws.east]\n    }\n" +
+				"    # randomness\n    random = {\n      version = \"~> 3.0\"\n    }\n  }\n}\n"),
+		},
+	}
+	if diff := cmp.Diff(want, changes); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+
+	ingestor = newMemIngestor(map[string]string{
+		"a.tf": "terraform {\n  required_providers {\n    aws = \"~> 4.0\"\n  }\n}\n",
+		"b.tf": "terraform {\n  required_providers {\n    aws = {\n      version = \"~> 5.0\"\n    }\n  }\n}\n",
+	})
+	_, err = ingestor.MergeProviders([]string{"a.tf", "b.tf"}, "a.tf")
+	var conflicts *hclsort.ProviderConflictError
+	if !errors.As(err, &conflicts) {
+		t.Fatalf("expected a ProviderConflictError, got %v", err)
+	}
+	wantErr := `b.tf:4,17: conflicting version "~> 5.0" for provider "aws", first declared as "~> 4.0" at a.tf:3,11`
+	if diff := cmp.Diff(wantErr, err.Error()); diff != "" {
+		t.Errorf("unexpected error (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeProvidersSkipsFilteredFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.tf": "terraform {\n  required_providers {\n    aws = {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+		"vendor.tf": "terraform {\n  required_providers {\n    random = {\n      source = \"hashicorp/random\"\n" +
+			"    }\n  }\n}\n",
+	})
+	filter, err := hclsort.NewPathFilter(nil, []string{"vendor.tf"}, false)
+	if err != nil {
+		t.Fatalf("NewPathFilter failed: %v", err)
+	}
+
+	paths, err := filter.ModuleFiles(dir, dir)
+	if err != nil {
+		t.Fatalf("ModuleFiles failed: %v", err)
+	}
+	changes, err := hclsort.NewIngestor().MergeProviders(paths, filepath.Join(dir, "versions.tf"))
+	if err != nil {
+		t.Fatalf("MergeProviders failed: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, change := range changes {
+		got[filepath.Base(change.Path)] = string(change.After)
+	}
+	want := map[string]string{
+		"main.tf":     "",
+		"versions.tf": "terraform {\n  required_providers {\n    aws = {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,261 @@
+package hclsort
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// FileChange is a planned change of a single file. A nil Before means the file is
+// created, a nil After means it is deleted.
+type FileChange struct {
+	Path   string
+	Before []byte
+	After  []byte
+}
+
+// SplitTargets returns the base name of the conventional file for each block type that
+// Split moves.
+func SplitTargets() map[string]string {
+	return map[string]string{
+		"variable":  "variables",
+		"output":    "outputs",
+		"terraform": "versions",
+	}
+}
+
+// splitFile is a file of the module being split.
+type splitFile struct {
+	original []byte
+	exists   bool
+	style    TextStyle
+	// touched is set when blocks were moved out of or into the file.
+	touched bool
+	// content is the current text, with LF line endings and no byte order mark.
+	content []byte
+}
+
+// Split plans moving the blocks of the given files, which belong to one module, into the
+// conventional files of SplitTargets: variables.tf, outputs.tf and versions.tf, or the
+// .tofu equivalents for .tofu files. Only the required_version and required_providers
+// of a terraform block move to versions.tf; a block that also holds other settings, such
+// as a backend, keeps them where it is. Moved blocks keep their leading comments. Every
+// changed file is sorted, and files left empty are deleted. Override files are left
+// alone. Nothing is written; the changes are returned in path order.
+func (i *Ingestor) Split(paths []string) ([]FileChange, error) {
+	files := map[string]*splitFile{}
+	load := func(path string) (*splitFile, error) {
+		if f, ok := files[path]; ok {
+			return f, nil
+		}
+		src, err := i.fileSystem().ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		style := DetectTextStyle(src)
+		f := &splitFile{original: src, exists: err == nil, style: style, content: style.Normalize(src)}
+		files[path] = f
+		return f, nil
+	}
+
+	moved := map[string][]byte{}
+	var targets []string
+	for _, path := range paths {
+		if IsOverrideFile(path) {
+			continue
+		}
+		f, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		rest, taken, err := takeSplitBlocks(f.content, path)
+		if err != nil {
+			return nil, err
+		}
+		if len(taken) > 0 {
+			f.content = rest
+			f.touched = true
+		}
+		for target, text := range taken {
+			if _, ok := moved[target]; !ok {
+				targets = append(targets, target)
+				if _, loadErr := load(target); loadErr != nil {
+					return nil, loadErr
+				}
+				if !files[target].exists {
+					files[target].style = f.style
+				}
+			}
+			moved[target] = append(moved[target], text...)
+		}
+	}
+
+	for _, target := range targets {
+		f := files[target]
+		f.content = trimTrailingBlankLines(f.content)
+		if len(f.content) > 0 {
+			f.content = append(f.content, '\n')
+		}
+		f.content = append(f.content, moved[target]...)
+		f.touched = true
+	}
+
+	return i.splitChanges(files)
+}
+
+// takeSplitBlocks removes the blocks of src that belong in another file. It returns the
+// remaining text and the removed text of each target file.
+func takeSplitBlocks(src []byte, path string) ([]byte, map[string][]byte, error) {
+	body, err := parseSyntaxBody(src, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targets := SplitTargets()
+	taken := map[string][]byte{}
+	var removed []span
+	floor := 0
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+
+		base, ok := targets[block.Type]
+		if !ok {
+			continue
+		}
+		target := filepath.Join(filepath.Dir(path), base+filepath.Ext(path))
+		if target == path {
+			continue
+		}
+
+		if block.Type == "terraform" {
+			inner, whole := versionItems(src, block.Body)
+			if !whole {
+				if len(inner) > 0 {
+					taken[target] = append(taken[target], versionsBlock(src, inner)...)
+					removed = append(removed, inner...)
+				}
+				continue
+			}
+		}
+
+		text := append([]byte{}, src[item.start:item.end]...)
+		taken[target] = append(taken[target], append(trimTrailingBlankLines(text), "\n\n"...)...)
+
+		// The blank lines after a removed block go with it.
+		for item.end < len(src) && src[item.end] == '\n' {
+			item.end++
+		}
+		removed = append(removed, item)
+	}
+
+	if len(removed) == 0 {
+		return src, nil, nil
+	}
+	return replaceSpans(src, removed, make([][]byte, len(removed))), taken, nil
+}
+
+// versionItems returns the spans of the required_version attribute and the
+// required_providers blocks of a terraform block body, each with the blank lines that
+// separate it from the next item. It also reports whether the body holds nothing else,
+// in which case the whole block moves.
+func versionItems(src []byte, body *hclsyntax.Body) ([]span, bool) {
+	type bodyItem struct {
+		rng     hcl.Range
+		version bool
+	}
+	var all []bodyItem
+	for _, attr := range body.Attributes {
+		all = append(all, bodyItem{rng: attr.SrcRange, version: attr.Name == "required_version"})
+	}
+	for _, block := range body.Blocks {
+		all = append(all, bodyItem{rng: block.Range(), version: block.Type == "required_providers"})
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a].rng.Start.Byte < all[b].rng.Start.Byte })
+
+	var spans []span
+	whole := true
+	floor := body.SrcRange.Start.Byte + 1
+	for idx, item := range all {
+		itemRange := itemSpan(src, item.rng, floor)
+		if !item.version {
+			whole = false
+			floor = itemRange.end
+			continue
+		}
+		if idx == len(all)-1 {
+			// The last item takes the blank lines above it, so none is left before the brace.
+			for itemRange.start > floor && src[itemRange.start-1] == '\n' {
+				itemRange.start--
+			}
+		} else {
+			for itemRange.end < len(src) && src[itemRange.end] == '\n' {
+				itemRange.end++
+			}
+		}
+		floor = itemRange.end
+		spans = append(spans, itemRange)
+	}
+	return spans, whole
+}
+
+// versionsBlock returns a terraform block holding the text of items, separated by blank
+// lines.
+func versionsBlock(src []byte, items []span) []byte {
+	text := []byte("terraform {\n")
+	for idx, item := range items {
+		if idx > 0 {
+			text = append(text, '\n')
+		}
+		text = append(text, trimTrailingBlankLines(bytes.TrimLeft(src[item.start:item.end], "\n"))...)
+	}
+	return append(text, "}\n\n"...)
+}
+
+// splitChanges sorts every touched file and returns the ones that differ from their
+// original content.
+func (i *Ingestor) splitChanges(files map[string]*splitFile) ([]FileChange, error) {
+	var changes []FileChange
+	for path, f := range files {
+		if !f.touched {
+			continue
+		}
+		change := FileChange{Path: path}
+		if f.exists {
+			change.Before = f.original
+		}
+
+		if len(bytes.TrimSpace(f.content)) > 0 {
+			after, err := i.sortWithStyle(f.content, path, f.style.WithLineEndings(i.LineEndings))
+			if err != nil {
+				return nil, err
+			}
+			change.After = after
+		}
+
+		if f.exists && change.After != nil && bytes.Equal(change.Before, change.After) {
+			continue
+		}
+		if !f.exists && change.After == nil {
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(a, b int) bool { return changes[a].Path < changes[b].Path })
+	return changes, nil
+}
+
+// trimTrailingBlankLines removes trailing whitespace, keeping a final newline.
+func trimTrailingBlankLines(text []byte) []byte {
+	text = bytes.TrimRight(text, " \t\n")
+	if len(text) == 0 {
+		return text
+	}
+	return append(text, '\n')
+}
@@ -0,0 +1,83 @@
+package hclsort_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	ingestor := newMemIngestor(map[string]string{
+		"mod/main.tf": "terraform {\n  required_version = \">= 1.0\"\n}\n\n" +
+			"# Region to deploy to.\nvariable \"region\" {}\n\nresource \"null_resource\" \"x\" {}\n",
+		"mod/variables.tf": "variable \"name\" {}\n",
+		"mod/outputs.tf":   "output \"id\" {\n  value = 1\n}\n",
+		"mod/extra.tf":     "variable \"b\" {}\n",
+	})
+
+	changes, err := ingestor.Split([]string{"mod/extra.tf", "mod/main.tf", "mod/outputs.tf", "mod/variables.tf"})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, change := range changes {
+		switch {
+		case change.After == nil:
+			got[change.Path] = "<deleted>"
+		case change.Before == nil:
+			got[change.Path] = "<created>\n" + string(change.After)
+		default:
+			got[change.Path] = string(change.After)
+		}
+	}
+	want := map[string]string{
+		filepath.Join("mod", "extra.tf"): "<deleted>",
+		filepath.Join("mod", "main.tf"):  "resource \"null_resource\" \"x\" {}\n",
+		filepath.Join("mod", "variables.tf"): "variable \"b\" {}\n\nvariable \"name\" {}\n\n" +
+			"# Region to deploy to.\nvariable \"region\" {}\n",
+		filepath.Join("mod", "versions.tf"): "<created>\nterraform {\n  required_version = \">= 1.0\"\n}\n",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+
+	t.Run("Terraform block with a backend", func(t *testing.T) {
+		t.Parallel()
+
+		backend := newMemIngestor(map[string]string{
+			"main.tf": "terraform {\n  required_version = \">= 1.0\"\n\n  backend \"s3\" {\n    bucket = \"state\"\n  }\n\n" +
+				"  required_providers {\n    aws = {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+			"main_override.tf": "terraform {\n  required_version = \">= 1.5\"\n}\n",
+		})
+
+		backendChanges, splitErr := backend.Split([]string{"main.tf", "main_override.tf"})
+		if splitErr != nil {
+			t.Fatalf("Split failed: %v", splitErr)
+		}
+		gotChanges := map[string]string{}
+		for _, change := range backendChanges {
+			gotChanges[change.Path] = string(change.After)
+		}
+		wantChanges := map[string]string{
+			"main.tf": "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n  }\n}\n",
+			"versions.tf": "terraform {\n  required_version = \">= 1.0\"\n\n  required_providers {\n" +
+				"    aws = {\n      source = \"hashicorp/aws\"\n    }\n  }\n}\n",
+		}
+		if diff := cmp.Diff(wantChanges, gotChanges); diff != "" {
+			t.Errorf("unexpected changes (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Invalid file", func(t *testing.T) {
+		t.Parallel()
+
+		broken := newMemIngestor(map[string]string{"main.tf": "variable \"a\" {"})
+		if _, splitErr := broken.Split([]string{"main.tf"}); splitErr == nil {
+			t.Error("Expected error for invalid HCL but got nil")
+		}
+	})
+}
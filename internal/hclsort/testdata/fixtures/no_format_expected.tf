@@ -0,0 +1,31 @@
+# header comment
+
+foo = 1
+
+resource "r" "x" {}
+
+locals {
+  a = {
+    q = 1
+  }
+  m = 2
+  # zz
+  z    = 1 # trailing
+}
+
+terraform {
+  required_providers {
+    a = {source="a"}
+    z = {source="z"}
+  }
+}
+
+# dangling
+variable "a" {
+  y = 2 # c
+}
+
+# about b
+variable "b" {
+  x    = 1
+}
@@ -0,0 +1,31 @@
+# header comment
+
+foo = 1
+
+# about b
+variable "b" {
+  x    = 1
+}
+
+
+
+resource "r" "x" {}
+# dangling
+variable "a" {
+  y = 2 # c
+}
+locals {
+  # zz
+  z    = 1 # trailing
+  a = {
+    q = 1
+  }
+
+  m = 2
+}
+terraform {
+  required_providers {
+    z = {source="z"}
+    a = {source="a"}
+  }
+}
@@ -0,0 +1,91 @@
+package hclsort
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// LineEndings selects the line terminator used when writing sorted content.
+type LineEndings string
+
+const (
+	// LineEndingsAuto keeps the line terminator detected in the input.
+	LineEndingsAuto LineEndings = "auto"
+	// LineEndingsLF always writes "\n".
+	LineEndingsLF LineEndings = "lf"
+	// LineEndingsCRLF always writes "\r\n".
+	LineEndingsCRLF LineEndings = "crlf"
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of UTF-8 files.
+const utf8BOM = "\xEF\xBB\xBF"
+
+// TextStyle describes the byte-level conventions of a source file that tfsort preserves.
+type TextStyle struct {
+	BOM          bool
+	CRLF         bool
+	FinalNewline bool
+}
+
+// ParseLineEndings converts a flag value into a LineEndings mode.
+func ParseLineEndings(value string) (LineEndings, error) {
+	switch mode := LineEndings(value); mode {
+	case LineEndingsAuto, LineEndingsLF, LineEndingsCRLF:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid line endings '%s': must be one of auto, lf, crlf", value)
+	}
+}
+
+// DetectTextStyle inspects src for a UTF-8 BOM, its dominant line terminator and
+// whether it ends with a newline.
+func DetectTextStyle(src []byte) TextStyle {
+	body := bytes.TrimPrefix(src, []byte(utf8BOM))
+	crlf := bytes.Count(body, []byte("\r\n"))
+	lf := bytes.Count(body, []byte("\n")) - crlf
+
+	return TextStyle{
+		BOM:          len(body) != len(src),
+		CRLF:         crlf > lf,
+		FinalNewline: len(body) == 0 || bytes.HasSuffix(body, []byte("\n")),
+	}
+}
+
+// WithLineEndings returns a copy of s that uses the line terminator selected by mode.
+func (s TextStyle) WithLineEndings(mode LineEndings) TextStyle {
+	switch mode {
+	case LineEndingsLF:
+		s.CRLF = false
+	case LineEndingsCRLF:
+		s.CRLF = true
+	case LineEndingsAuto:
+	}
+	return s
+}
+
+// Normalize strips the BOM, converts every "\r\n" to "\n" and terminates the last line
+// so the content can be parsed and formatted consistently.
+func (s TextStyle) Normalize(src []byte) []byte {
+	src = bytes.TrimPrefix(src, []byte(utf8BOM))
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	if !s.FinalNewline {
+		src = append(src, '\n')
+	}
+	return src
+}
+
+// Restore reapplies the trailing newline, line terminator and BOM described by s to
+// normalized content that ends with a single "\n".
+func (s TextStyle) Restore(content []byte) []byte {
+	out := content
+	if !s.FinalNewline {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+	if s.CRLF {
+		out = bytes.ReplaceAll(out, []byte("\n"), []byte("\r\n"))
+	}
+	if s.BOM {
+		out = append([]byte(utf8BOM), out...)
+	}
+	return out
+}
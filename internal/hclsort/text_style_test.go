@@ -0,0 +1,67 @@
+package hclsort_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLineEndings(t *testing.T) {
+	const bom = "\xEF\xBB\xBF"
+	const unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	const sorted = "variable \"a\" {}\n\nvariable \"b\" {}\n"
+
+	crlf := func(s string) string { return strings.ReplaceAll(s, "\n", "\r\n") }
+
+	tests := []struct {
+		name        string
+		lineEndings hclsort.LineEndings
+		input       string
+		want        string
+	}{
+		{name: "LF kept", lineEndings: hclsort.LineEndingsAuto, input: unsorted, want: sorted},
+		{name: "CRLF kept", lineEndings: hclsort.LineEndingsAuto, input: crlf(unsorted), want: crlf(sorted)},
+		{name: "BOM kept", lineEndings: hclsort.LineEndingsAuto, input: bom + crlf(unsorted), want: bom + crlf(sorted)},
+		{
+			name:        "Missing final newline kept",
+			lineEndings: hclsort.LineEndingsAuto,
+			input:       strings.TrimSuffix(unsorted, "\n"),
+			want:        strings.TrimSuffix(sorted, "\n"),
+		},
+		{name: "Forced LF", lineEndings: hclsort.LineEndingsLF, input: crlf(unsorted), want: sorted},
+		{name: "Forced CRLF", lineEndings: hclsort.LineEndingsCRLF, input: unsorted, want: crlf(sorted)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "variables.tf")
+			if err := os.WriteFile(path, []byte(tc.input), 0600); err != nil {
+				t.Fatalf("Failed to create input file: %v", err)
+			}
+
+			ingestor := hclsort.NewIngestor()
+			ingestor.LineEndings = tc.lineEndings
+			if err := ingestor.Parse(path, "", false, false); err != nil {
+				t.Fatalf("Parse failed unexpectedly: %v", err)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read rewritten file: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Invalid mode", func(t *testing.T) {
+		if _, err := hclsort.ParseLineEndings("cr"); err == nil {
+			t.Error("Expected error for invalid line endings but got nil")
+		}
+	})
+}
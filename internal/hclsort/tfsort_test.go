@@ -3,6 +3,7 @@ package hclsort_test
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -91,6 +92,25 @@ func captureOutput(t *testing.T, action func()) string {
 	return string(outBytes)
 }
 
+// newMemIngestor returns an Ingestor that reads and writes the given files in memory
+// and discards its warnings.
+func newMemIngestor(files map[string]string) *hclsort.Ingestor {
+	ingestor := hclsort.NewIngestor()
+	ingestor.FS = hclsort.NewMemFileSystem(files)
+	ingestor.Logger = log.New(io.Discard, "", 0)
+	return ingestor
+}
+
+// writeFiles creates the given files, named relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
 func TestCheckFileExtension(t *testing.T) {
 	setupTestDir(t)
 
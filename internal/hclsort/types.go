@@ -1,14 +1,51 @@
 package hclsort
 
-import "github.com/hashicorp/hcl/v2/hclwrite"
+import (
+	"io"
+	"log"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
 
 // StdInPathIdentifier is a marker for when input is read from stdin.
 const StdInPathIdentifier = "<stdin>"
 
+// SymlinkPolicy controls how a symbolic link is treated when it would be rewritten in place.
+type SymlinkPolicy string
+
+const (
+	// SymlinkFollow writes the sorted content to the file the link points to.
+	SymlinkFollow SymlinkPolicy = "follow"
+	// SymlinkSkip leaves the link and its target untouched.
+	SymlinkSkip SymlinkPolicy = "skip"
+	// SymlinkError fails when asked to rewrite a link.
+	SymlinkError SymlinkPolicy = "error"
+)
+
 // Ingestor is a struct that contains the logic for parsing Terraform files.
 type Ingestor struct {
 	AllowedTypes  map[string]bool
 	AllowedBlocks map[string]bool
+	// SymlinkPolicy decides how a symbolic link is treated when it would be rewritten.
+	SymlinkPolicy SymlinkPolicy
+	// LineEndings selects the line terminator of written files.
+	LineEndings LineEndings
+	// NoFormat only reorders blocks and attributes and leaves all other bytes untouched.
+	NoFormat bool
+	// MergeLocals combines all locals blocks of a file into a single sorted block.
+	MergeLocals bool
+	// Profiles are tried, in order, before the built-in profiles to pick how a file is sorted.
+	Profiles []Profile
+	// IncludeOverrides sorts override files, which SkipFile leaves alone otherwise.
+	IncludeOverrides bool
+	// Stdin and Stdout replace the process's standard streams when not nil.
+	Stdin  io.Reader
+	Stdout io.Writer
+	// Logger receives warnings. NewIngestor sets it to a logger writing to the process's
+	// stderr; nil discards them.
+	Logger *log.Logger
+	// FS replaces the operating system's file system when not nil.
+	FS FileSystem
 }
 
 // SortableBlock holds information needed for sorting.
@@ -0,0 +1,109 @@
+package hclsort
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// UnsortedItem is a block or attribute that the sorting pipeline would move.
+type UnsortedItem struct {
+	// Description names the item, e.g. `variable "region"`.
+	Description string
+	// Range covers the item's header: the block type and labels, or the attribute name.
+	Range hcl.Range
+}
+
+// TextEdit replaces the bytes [Start, End) of a source with Text.
+type TextEdit struct {
+	Start int
+	End   int
+	Text  []byte
+}
+
+// FindUnsorted reports the blocks and attributes of src that are not in sorted order
+// under the profile for filename.
+func (i *Ingestor) FindUnsorted(src []byte, filename string) ([]UnsortedItem, error) {
+	return findUnsorted(src, filename, i.Profile(filename))
+}
+
+// findUnsorted reports the blocks and attributes of src that profile would move.
+func findUnsorted(src []byte, filename string, profile Profile) ([]UnsortedItem, error) {
+	body, err := parseSyntaxBody(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []UnsortedItem
+	profile.walkSyntaxBodies(body, nil, func(path []string, nested *hclsyntax.Body) {
+		for i, idx := range profile.syntaxOrder(src, path, nested.Blocks) {
+			if i == idx {
+				continue
+			}
+			block := nested.Blocks[idx]
+			items = append(items, UnsortedItem{
+				Description: describeBlock(block),
+				Range:       block.DefRange(),
+			})
+		}
+
+		if !profile.sortsAttributes(path) {
+			return
+		}
+		attrs := attributesInSourceOrder(nested)
+		for i, idx := range attributeOrder(attrs) {
+			if i == idx {
+				continue
+			}
+			items = append(items, UnsortedItem{
+				Description: fmt.Sprintf("%q", attrs[idx].Name),
+				Range:       attrs[idx].NameRange,
+			})
+		}
+	})
+
+	return items, nil
+}
+
+// SortRange sorts only the top-level blocks of src that overlap the byte range [start, end).
+// It returns the edit to apply, or nil when no block overlaps the range.
+func (i *Ingestor) SortRange(src []byte, filename string, start, end int) (*TextEdit, error) {
+	body, err := parseSyntaxBody(src, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	region := span{start: -1}
+	floor := 0
+	for _, block := range body.Blocks {
+		item := itemSpan(src, block.Range(), floor)
+		floor = item.end
+		if item.end <= start || item.start >= end {
+			continue
+		}
+		if region.start < 0 {
+			region.start = item.start
+		}
+		region.end = item.end
+	}
+	if region.start < 0 {
+		return nil, nil //nolint:nilnil // no block overlaps the range, so there is nothing to edit
+	}
+
+	sorted, err := i.Sort(src[region.start:region.end], filename)
+	if err != nil {
+		return nil, err
+	}
+	return &TextEdit{Start: region.start, End: region.end, Text: sorted}, nil
+}
+
+// describeBlock renders a block's type and labels, e.g. `variable "region"`.
+func describeBlock(block *hclsyntax.Block) string {
+	parts := []string{block.Type}
+	for _, label := range block.Labels {
+		parts = append(parts, fmt.Sprintf("%q", label))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,82 @@
+package hclsort_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindUnsorted(t *testing.T) {
+	t.Parallel()
+
+	const input = `locals {
+  b = 1
+  a = 2
+}
+
+variable "b" {}
+
+variable "a" {}
+`
+	ingestor := hclsort.NewIngestor()
+	items, err := ingestor.FindUnsorted([]byte(input), "test.tf")
+	if err != nil {
+		t.Fatalf("FindUnsorted failed: %v", err)
+	}
+
+	got := make([]string, 0, len(items))
+	for _, item := range items {
+		got = append(got, fmt.Sprintf("%s@%d", item.Description, item.Range.Start.Line))
+	}
+	want := []string{`variable "a"@8`, `variable "b"@6`, `"a"@3`, `"b"@2`}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected unsorted items (-want +got):\n%s", diff)
+	}
+
+	if _, err = ingestor.FindUnsorted([]byte(`variable "a" {`), "test.tf"); err == nil {
+		t.Error("Expected error for invalid HCL but got nil")
+	}
+}
+
+func TestSortRange(t *testing.T) {
+	t.Parallel()
+
+	const input = "variable \"d\" {}\nvariable \"c\" {}\n\noutput \"b\" {}\noutput \"a\" {}\n"
+	src := []byte(input)
+	ingestor := hclsort.NewIngestor()
+
+	t.Run("Sorts only the selected blocks", func(t *testing.T) {
+		t.Parallel()
+
+		start := strings.Index(input, "output \"b\"")
+		edit, err := ingestor.SortRange(src, "test.tf", start, len(input))
+		if err != nil {
+			t.Fatalf("SortRange failed: %v", err)
+		}
+		if edit == nil {
+			t.Fatal("expected an edit")
+		}
+
+		got := input[:edit.Start] + string(edit.Text) + input[edit.End:]
+		want := "variable \"d\" {}\nvariable \"c\" {}\n\noutput \"a\" {}\n\noutput \"b\" {}\n"
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected output (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Selection outside of any block", func(t *testing.T) {
+		t.Parallel()
+
+		blank := strings.Index(input, "\n\n") + 1
+		edit, err := ingestor.SortRange(src, "test.tf", blank, blank)
+		if err != nil {
+			t.Fatalf("SortRange failed: %v", err)
+		}
+		if edit != nil {
+			t.Errorf("expected no edit, got %+v", edit)
+		}
+	})
+}
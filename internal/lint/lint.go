@@ -0,0 +1,129 @@
+// Package lint checks Terraform modules against tfsort's module standards.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Severity is how seriously a rule violation is reported.
+type Severity string
+
+const (
+	// SeverityOff disables a rule.
+	SeverityOff Severity = "off"
+	// SeverityWarning reports violations without failing.
+	SeverityWarning Severity = "warning"
+	// SeverityError reports violations and fails the lint run.
+	SeverityError Severity = "error"
+)
+
+// ParseSeverity validates a severity name.
+func ParseSeverity(value string) (Severity, error) {
+	switch severity := Severity(value); severity {
+	case SeverityOff, SeverityWarning, SeverityError:
+		return severity, nil
+	default:
+		return "", fmt.Errorf("invalid severity '%s': must be one of off, warning, error", value)
+	}
+}
+
+// Diagnostic is a single rule violation.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Range    hcl.Range
+}
+
+// String formats the diagnostic as "file:line,column: severity: message (rule)".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d,%d: %s: %s (%s)",
+		d.Range.Filename, d.Range.Start.Line, d.Range.Start.Column, d.Severity, d.Message, d.Rule)
+}
+
+// finding is a violation found by a rule, before its severity is applied.
+type finding struct {
+	message string
+	rng     hcl.Range
+}
+
+// Rule is a module standard that can be checked.
+type Rule struct {
+	Name        string
+	Description string
+	// Severity is the severity used when the configuration does not set one.
+	Severity Severity
+	check    func(module *hclsort.Module, load Loader) []finding
+}
+
+// Loader loads the module in a directory.
+type Loader func(dir string) (*hclsort.Module, error)
+
+// Linter checks modules against the enabled rules.
+type Linter struct {
+	// Load loads the modules that are called with a local source. Rules that follow
+	// module calls report nothing when it is nil.
+	Load Loader
+
+	rules      []Rule
+	severities map[string]Severity
+}
+
+// New returns a Linter that applies the given severities, by rule name, over the
+// default severity of each rule.
+func New(severities map[string]string) (*Linter, error) {
+	l := &Linter{rules: Rules(), severities: map[string]Severity{}}
+	for _, rule := range l.rules {
+		l.severities[rule.Name] = rule.Severity
+	}
+
+	for name, value := range severities {
+		if _, ok := l.severities[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule '%s'", name)
+		}
+		severity, err := ParseSeverity(value)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s': %w", name, err)
+		}
+		l.severities[name] = severity
+	}
+	return l, nil
+}
+
+// Enabled reports whether the rule with the given name is checked.
+func (l *Linter) Enabled(name string) bool {
+	severity, ok := l.severities[name]
+	return ok && severity != SeverityOff
+}
+
+// Lint checks module and returns the diagnostics in file and position order.
+func (l *Linter) Lint(module *hclsort.Module) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range l.rules {
+		severity := l.severities[rule.Name]
+		if severity == SeverityOff {
+			continue
+		}
+		for _, f := range rule.check(module, l.Load) {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     rule.Name,
+				Severity: severity,
+				Message:  f.message,
+				Range:    f.rng,
+			})
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(a, b int) bool {
+		ra, rb := diagnostics[a].Range, diagnostics[b].Range
+		if ra.Filename != rb.Filename {
+			return ra.Filename < rb.Filename
+		}
+		return ra.Start.Byte < rb.Start.Byte
+	})
+	return diagnostics
+}
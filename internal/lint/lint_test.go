@@ -0,0 +1,210 @@
+package lint_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/lint"
+	"github.com/google/go-cmp/cmp"
+)
+
+func lintSource(t *testing.T, severities map[string]string, files map[string]string) []string {
+	t.Helper()
+
+	module := &hclsort.Module{}
+	for _, name := range []string{"main.tf", "outputs.tf"} {
+		src, ok := files[name]
+		if !ok {
+			continue
+		}
+		file, err := hclsort.NewModuleFile(name, []byte(src))
+		if err != nil {
+			t.Fatalf("NewModuleFile(%s) failed: %v", name, err)
+		}
+		module.Files = append(module.Files, file)
+	}
+
+	linter, err := lint.New(severities)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	var got []string
+	for _, diagnostic := range linter.Lint(module) {
+		got = append(got, diagnostic.String())
+	}
+	return got
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"main.tf": "variable \"dbPassword\" {\n  type      = string\n  sensitive = true\n}\n\n" +
+			"variable \"region\" {\n  description = \"Region.\"\n}\n\n" +
+			"locals {\n  Bad = 1\n}\n\nresource \"aws_instance\" \"web_server\" {}\n",
+		"outputs.tf": "output \"conn\" {\n  description = \"Connection.\"\n  value       = var.dbPassword\n}\n\n" +
+			"output \"hidden\" {\n  value     = sensitive(\"a\")\n  sensitive = true\n}\n\n" +
+			"output \"token\" {\n  description = \"Token.\"\n  value       = sensitive(\"b\")\n}\n",
+	}
+
+	tests := []struct {
+		name       string
+		severities map[string]string
+		want       []string
+	}{
+		{
+			name: "Defaults",
+			want: []string{
+				`main.tf:1,1: warning: variable "dbPassword" has no description (variable_description)`,
+				`main.tf:1,10: warning: variable name "dbPassword" is not snake_case (snake_case)`,
+				`main.tf:6,1: warning: variable "region" has no type (variable_type)`,
+				`main.tf:6,1: warning: variable "region" is declared but not used (unused_variable)`,
+				`main.tf:11,3: warning: local value "Bad" is declared but not used (unused_local)`,
+				`main.tf:11,3: warning: local value name "Bad" is not snake_case (snake_case)`,
+				`outputs.tf:3,17: error: output "conn" exposes sensitive variable "dbPassword" without sensitive = true (sensitive_output)`,
+				`outputs.tf:6,1: warning: output "hidden" has no description (output_description)`,
+				`outputs.tf:13,17: error: output "token" exposes a sensitive value without sensitive = true (sensitive_output)`,
+			},
+		},
+		{
+			name: "Configured severities",
+			severities: map[string]string{
+				"variable_description": "error",
+				"snake_case":           "off",
+				"sensitive_output":     "off",
+				"output_description":   "off",
+				"unused_variable":      "off",
+				"unused_local":         "off",
+			},
+			want: []string{
+				`main.tf:1,1: error: variable "dbPassword" has no description (variable_description)`,
+				`main.tf:6,1: warning: variable "region" has no type (variable_type)`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := lintSource(t, tc.severities, files)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLintReferences(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"main.tf": "variable \"name\" {\n  validation {\n    condition     = length(var.name) > 0\n" +
+			"    error_message = \"Empty.\"\n  }\n}\n\n" +
+			"variable \"used\" {}\n\nlocals {\n  prefix = \"${var.used}-\"\n  tags   = {}\n}\n\n" +
+			"resource \"null_resource\" \"this\" {\n  triggers = {\n    name = local.prefix\n" +
+			"    zone = var.zone\n  }\n\n  dynamic \"x\" {\n    for_each = local.missing\n    content {}\n  }\n}\n",
+	}
+	severities := map[string]string{
+		"variable_description": "off",
+		"variable_type":        "off",
+	}
+
+	want := []string{
+		`main.tf:1,1: warning: variable "name" is declared but not used (unused_variable)`,
+		`main.tf:12,3: warning: local value "tags" is declared but not used (unused_local)`,
+		`main.tf:18,12: error: reference to undeclared variable "zone" (undeclared_variable)`,
+		`main.tf:22,16: error: reference to undeclared local value "missing" (undeclared_local)`,
+	}
+	if diff := cmp.Diff(want, lintSource(t, severities, files)); diff != "" {
+		t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+	}
+}
+
+func TestUndeclaredVariables(t *testing.T) {
+	t.Parallel()
+
+	src := "variable \"region\" {}\n\nresource \"null_resource\" \"x\" {\n  triggers = {\n" +
+		"    a = var.subnets[0]\n    b = var.tags[\"env\"]\n    c = var.name\n    d = var.region\n" +
+		"    e = length(var.subnets)\n  }\n}\n"
+	file, err := hclsort.NewModuleFile("main.tf", []byte(src))
+	if err != nil {
+		t.Fatalf("NewModuleFile failed: %v", err)
+	}
+
+	var got []string
+	for _, v := range lint.UndeclaredVariables(&hclsort.Module{Files: []*hclsort.ModuleFile{file}}) {
+		got = append(got, fmt.Sprintf("%s %s %d", v.Name, v.Type, v.Range.Start.Line))
+	}
+	want := []string{"name any 7", "subnets list(any) 5", "tags map(any) 6"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected variables (-want +got):\n%s", diff)
+	}
+}
+
+func TestModuleArguments(t *testing.T) {
+	t.Parallel()
+
+	parse := func(path, src string) *hclsort.Module {
+		file, err := hclsort.NewModuleFile(path, []byte(src))
+		if err != nil {
+			t.Fatalf("NewModuleFile(%s) failed: %v", path, err)
+		}
+		return &hclsort.Module{Files: []*hclsort.ModuleFile{file}}
+	}
+	callee := parse("modules/net/variables.tf", "variable \"name\" {}\n\nvariable \"cidr\" {\n  default = \"\"\n}\n\n"+
+		"variable \"tags\" {}\n")
+	module := parse("root/main.tf", "module \"net\" {\n  tags   = {}\n  source = \"../modules/net\"\n  extra  = 1\n}\n\n"+
+		"module \"remote\" {\n  source = \"hashicorp/consul/aws\"\n  extra  = 1\n}\n\n"+
+		"module \"gone\" {\n  source = \"./gone\"\n}\n")
+	load := func(dir string) (*hclsort.Module, error) {
+		if dir == filepath.Join("modules", "net") {
+			return callee, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	linter, err := lint.New(map[string]string{"module_arguments": "error", "snake_case": "off"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if diagnostics := linter.Lint(module); len(diagnostics) > 0 {
+		t.Errorf("expected no diagnostics without a loader, got %v", diagnostics)
+	}
+
+	linter.Load = load
+	var got []string
+	for _, diagnostic := range linter.Lint(module) {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		`root/main.tf:1,1: error: module "net" does not set required variable "name" (module_arguments)`,
+		`root/main.tf:1,1: error: arguments of module "net" do not follow the declaration order of its source (module_arguments)`,
+		`root/main.tf:4,3: error: module "net" sets "extra", which its source does not declare (module_arguments)`,
+		`root/main.tf:13,12: error: module "gone": cannot read its source: not found (module_arguments)`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected diagnostics (-want +got):\n%s", diff)
+	}
+
+	orders := lint.ModuleArgumentOrders(module, load)
+	if diff := cmp.Diff(map[string][]string{"net": {"name", "cidr", "tags"}}, orders); diff != "" {
+		t.Errorf("unexpected orders (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewRejectsInvalidSeverities(t *testing.T) {
+	t.Parallel()
+
+	for _, severities := range []map[string]string{
+		{"no_such_rule": "warning"},
+		{"variable_type": "fatal"},
+	} {
+		if _, err := lint.New(severities); err == nil {
+			t.Errorf("expected an error for %v", severities)
+		}
+	}
+}
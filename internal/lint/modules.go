@@ -0,0 +1,153 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// moduleCall is a module block whose source is a local path.
+type moduleCall struct {
+	name   string
+	source *hclsyntax.Attribute
+	block  *hclsyntax.Block
+	// callee is the called module, or nil when it could not be loaded.
+	callee *hclsort.Module
+	err    error
+}
+
+// localModuleCalls returns the module blocks of module that have a local source, with
+// the called module loaded by load. Only sources starting with ./ or ../ are followed,
+// so no module is downloaded.
+func localModuleCalls(module *hclsort.Module, load Loader) []moduleCall {
+	var calls []moduleCall
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type != "module" || len(block.Labels) == 0 {
+				continue
+			}
+			attr, ok := block.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			source, ok := localSource(attr)
+			if !ok {
+				continue
+			}
+
+			call := moduleCall{name: block.Labels[0], source: attr, block: block}
+			call.callee, call.err = load(filepath.Join(filepath.Dir(file.Path), source))
+			if call.err == nil && len(call.callee.Files) == 0 {
+				call.callee, call.err = nil, errors.New("no configuration files found")
+			}
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// localSource returns the value of a source argument when it is a local path.
+func localSource(attr *hclsyntax.Attribute) (string, bool) {
+	value, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !value.Type().Equals(cty.String) || value.IsNull() {
+		return "", false
+	}
+	source := value.AsString()
+	return source, strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// arguments returns the names of the arguments of a module call, in source order.
+func (c moduleCall) arguments() []string {
+	attrs := make([]*hclsyntax.Attribute, 0, len(c.block.Body.Attributes))
+	for _, attr := range c.block.Body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(a, b int) bool { return attrs[a].SrcRange.Start.Byte < attrs[b].SrcRange.Start.Byte })
+
+	names := make([]string, len(attrs))
+	for i, attr := range attrs {
+		names[i] = attr.Name
+	}
+	return names
+}
+
+// variableNames returns the names of the variables of the called module, in declaration
+// order.
+func (c moduleCall) variableNames() []string {
+	var names []string
+	for _, v := range c.callee.Variables() {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// ModuleArgumentOrders returns the variables of every module that module calls with a
+// local source, in declaration order, by the name of the call. They are the order that
+// Ingestor.OrderModuleArguments puts the arguments of each call in. Calls whose module
+// cannot be loaded are left out.
+func ModuleArgumentOrders(module *hclsort.Module, load Loader) map[string][]string {
+	orders := map[string][]string{}
+	for _, call := range localModuleCalls(module, load) {
+		if call.err == nil {
+			orders[call.name] = call.variableNames()
+		}
+	}
+	return orders
+}
+
+// checkModuleArguments finds calls of local modules that set arguments the called module
+// does not declare, miss one of its required variables or do not follow the order of
+// its variables.
+func checkModuleArguments(module *hclsort.Module, load Loader) []finding {
+	if load == nil {
+		return nil
+	}
+
+	var findings []finding
+	for _, call := range localModuleCalls(module, load) {
+		source := call.source.Expr.Range()
+		if call.err != nil {
+			findings = append(findings, finding{
+				message: fmt.Sprintf("module %q: cannot read its source: %v", call.name, call.err),
+				rng:     source,
+			})
+			continue
+		}
+
+		variables := call.variableNames()
+		meta := hclsort.ModuleMetaArguments()
+		for _, name := range call.arguments() {
+			if !slices.Contains(meta, name) && !slices.Contains(variables, name) {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("module %q sets %q, which its source does not declare", call.name, name),
+					rng:     call.block.Body.Attributes[name].NameRange,
+				})
+			}
+		}
+
+		for _, v := range call.callee.Variables() {
+			if _, ok := call.block.Body.Attributes[v.Name]; v.Required && !ok {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("module %q does not set required variable %q", call.name, v.Name),
+					rng:     call.block.DefRange(),
+				})
+			}
+		}
+
+		order := hclsort.ModuleArgumentOrder(call.arguments(), variables)
+		if !sort.IntsAreSorted(order) {
+			findings = append(findings, finding{
+				message: fmt.Sprintf("arguments of module %q do not follow the declaration order of its source", call.name),
+				rng:     call.block.DefRange(),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Reference roots checked by the reference rules.
+const (
+	rootVar   = "var"
+	rootLocal = "local"
+)
+
+// reference is a var.<name> or local.<name> traversal.
+type reference struct {
+	name      string
+	rng       hcl.Range
+	traversal hcl.Traversal
+}
+
+// UndeclaredVariable is a variable that is referenced but has no variable block.
+type UndeclaredVariable struct {
+	Name string
+	// Type is a type constraint guessed from the references: list(any) or map(any) when
+	// the variable is indexed by a number or a string, and any otherwise.
+	Type string
+	// Range is the position of the first reference.
+	Range hcl.Range
+}
+
+// UndeclaredVariables returns the variables that module references without declaring
+// them, in name order.
+func UndeclaredVariables(module *hclsort.Module) []UndeclaredVariable {
+	declared := declarations(module, rootVar)
+	byName := map[string]*UndeclaredVariable{}
+	var names []string
+	for _, ref := range references(module, rootVar) {
+		if _, ok := declared[ref.name]; ok {
+			continue
+		}
+		v, ok := byName[ref.name]
+		if !ok {
+			v = &UndeclaredVariable{Name: ref.name, Type: "any", Range: ref.rng}
+			byName[ref.name] = v
+			names = append(names, ref.name)
+		}
+		if guess := guessType(ref.traversal); v.Type == "any" {
+			v.Type = guess
+		}
+	}
+
+	sort.Strings(names)
+	variables := make([]UndeclaredVariable, len(names))
+	for i, name := range names {
+		variables[i] = *byName[name]
+	}
+	return variables
+}
+
+// guessType guesses the type of a variable from how traversal, var.<name>..., uses it.
+func guessType(traversal hcl.Traversal) string {
+	// The step after var.<name> shows how the variable is used.
+	const usageStep = 2
+	if len(traversal) <= usageStep {
+		return "any"
+	}
+	index, ok := traversal[usageStep].(hcl.TraverseIndex)
+	if !ok || !index.Key.IsKnown() || index.Key.IsNull() {
+		return "any"
+	}
+	switch index.Key.Type() {
+	case cty.Number:
+		return "list(any)"
+	case cty.String:
+		return "map(any)"
+	default:
+		return "any"
+	}
+}
+
+// declarations returns the first declaration of every variable, for rootVar, or local
+// value, for rootLocal, by name.
+func declarations(module *hclsort.Module, root string) map[string]hcl.Range {
+	declared := map[string]hcl.Range{}
+	declare := func(name string, rng hcl.Range) {
+		if _, ok := declared[name]; !ok {
+			declared[name] = rng
+		}
+	}
+
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			switch {
+			case root == rootVar && block.Type == "variable" && len(block.Labels) > 0:
+				declare(block.Labels[0], block.DefRange())
+			case root == rootLocal && block.Type == "locals":
+				for _, attr := range block.Body.Attributes {
+					declare(attr.Name, attr.NameRange)
+				}
+			}
+		}
+	}
+	return declared
+}
+
+// references returns the traversals of module that start with root. A variable's
+// references to itself, as in its validation rules, are left out.
+func references(module *hclsort.Module, root string) []reference {
+	var refs []reference
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			self := ""
+			if block.Type == "variable" && len(block.Labels) > 0 && root == rootVar {
+				self = block.Labels[0]
+			}
+			refs = append(refs, bodyReferences(block.Body, root, self)...)
+		}
+		for _, attr := range file.Body.Attributes {
+			refs = append(refs, exprReferences(attr.Expr, root, "")...)
+		}
+	}
+	return refs
+}
+
+// bodyReferences returns the traversals in body and its nested blocks that start with
+// root and do not name self.
+func bodyReferences(body *hclsyntax.Body, root, self string) []reference {
+	var refs []reference
+	for _, attr := range body.Attributes {
+		refs = append(refs, exprReferences(attr.Expr, root, self)...)
+	}
+	for _, block := range body.Blocks {
+		refs = append(refs, bodyReferences(block.Body, root, self)...)
+	}
+	return refs
+}
+
+// exprReferences returns the traversals in expr that start with root and do not name self.
+func exprReferences(expr hclsyntax.Expression, root, self string) []reference {
+	var refs []reference
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != root || len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok && attr.Name != self {
+			refs = append(refs, reference{name: attr.Name, rng: traversal.SourceRange(), traversal: traversal})
+		}
+	}
+	return refs
+}
+
+// unusedDeclarations returns a check for variables or local values that are never referenced.
+func unusedDeclarations(kind, root string) func(*hclsort.Module, Loader) []finding {
+	return func(module *hclsort.Module, _ Loader) []finding {
+		used := map[string]bool{}
+		for _, ref := range references(module, root) {
+			used[ref.name] = true
+		}
+
+		var findings []finding
+		for name, rng := range declarations(module, root) {
+			if !used[name] {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("%s %q is declared but not used", kind, name),
+					rng:     rng,
+				})
+			}
+		}
+		return findings
+	}
+}
+
+// undeclaredReferences returns a check for references to variables or local values that
+// are not declared.
+func undeclaredReferences(kind, root string) func(*hclsort.Module, Loader) []finding {
+	return func(module *hclsort.Module, _ Loader) []finding {
+		declared := declarations(module, root)
+
+		var findings []finding
+		for _, ref := range references(module, root) {
+			if _, ok := declared[ref.name]; !ok {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("reference to undeclared %s %q", kind, ref.name),
+					rng:     ref.rng,
+				})
+			}
+		}
+		return findings
+	}
+}
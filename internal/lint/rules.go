@@ -0,0 +1,202 @@
+package lint
+
+import (
+	"fmt"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Rules returns every rule, in the order they are checked.
+func Rules() []Rule {
+	return []Rule{
+		{
+			Name:        "variable_description",
+			Description: "variables have a description",
+			Severity:    SeverityWarning,
+			check:       missingAttribute("variable", "description"),
+		},
+		{
+			Name:        "variable_type",
+			Description: "variables have a type",
+			Severity:    SeverityWarning,
+			check:       missingAttribute("variable", "type"),
+		},
+		{
+			Name:        "output_description",
+			Description: "outputs have a description",
+			Severity:    SeverityWarning,
+			check:       missingAttribute("output", "description"),
+		},
+		{
+			Name:        "sensitive_output",
+			Description: "outputs that expose sensitive values are marked sensitive",
+			Severity:    SeverityError,
+			check:       checkSensitiveOutputs,
+		},
+		{
+			Name:        "unused_variable",
+			Description: "every variable is referenced in the module",
+			Severity:    SeverityWarning,
+			check:       unusedDeclarations("variable", rootVar),
+		},
+		{
+			Name:        "undeclared_variable",
+			Description: "every var.<name> reference has a variable block",
+			Severity:    SeverityError,
+			check:       undeclaredReferences("variable", rootVar),
+		},
+		{
+			Name:        "unused_local",
+			Description: "every local value is referenced in the module",
+			Severity:    SeverityWarning,
+			check:       unusedDeclarations("local value", rootLocal),
+		},
+		{
+			Name:        "undeclared_local",
+			Description: "every local.<name> reference has a locals entry",
+			Severity:    SeverityError,
+			check:       undeclaredReferences("local value", rootLocal),
+		},
+		{
+			Name:        "snake_case",
+			Description: "variables, outputs, locals, resources, data sources and modules have snake_case names",
+			Severity:    SeverityWarning,
+			check:       checkSnakeCase,
+		},
+		{
+			Name:        "module_arguments",
+			Description: "local module calls set every required variable and only declared ones, in declaration order",
+			Severity:    SeverityOff,
+			check:       checkModuleArguments,
+		},
+	}
+}
+
+// forEachBlock calls fn for every top-level block of module with the given type.
+func forEachBlock(module *hclsort.Module, blockType string, fn func(block *hclsyntax.Block)) {
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			if block.Type == blockType {
+				fn(block)
+			}
+		}
+	}
+}
+
+// missingAttribute returns a check for labelled blocks of blockType without attribute name.
+func missingAttribute(blockType, name string) func(*hclsort.Module, Loader) []finding {
+	return func(module *hclsort.Module, _ Loader) []finding {
+		var findings []finding
+		forEachBlock(module, blockType, func(block *hclsyntax.Block) {
+			if _, ok := block.Body.Attributes[name]; !ok && len(block.Labels) > 0 {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("%s %q has no %s", blockType, block.Labels[0], name),
+					rng:     block.DefRange(),
+				})
+			}
+		})
+		return findings
+	}
+}
+
+// checkSensitiveOutputs finds outputs that are not marked sensitive but whose value
+// refers to a sensitive variable or calls the sensitive function.
+func checkSensitiveOutputs(module *hclsort.Module, _ Loader) []finding {
+	sensitiveVars := map[string]bool{}
+	forEachBlock(module, "variable", func(block *hclsyntax.Block) {
+		if len(block.Labels) > 0 && isTrue(block.Body.Attributes["sensitive"]) {
+			sensitiveVars[block.Labels[0]] = true
+		}
+	})
+
+	var findings []finding
+	forEachBlock(module, "output", func(block *hclsyntax.Block) {
+		value, ok := block.Body.Attributes["value"]
+		if !ok || len(block.Labels) == 0 || isTrue(block.Body.Attributes["sensitive"]) {
+			return
+		}
+		name := block.Labels[0]
+
+		for _, traversal := range value.Expr.Variables() {
+			if traversal.RootName() != rootVar || len(traversal) < 2 {
+				continue
+			}
+			if attr, isAttr := traversal[1].(hcl.TraverseAttr); isAttr && sensitiveVars[attr.Name] {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("output %q exposes sensitive variable %q without sensitive = true", name, attr.Name),
+					rng:     traversal.SourceRange(),
+				})
+			}
+		}
+
+		_ = hclsyntax.VisitAll(value.Expr, func(node hclsyntax.Node) hcl.Diagnostics {
+			if call, isCall := node.(*hclsyntax.FunctionCallExpr); isCall && call.Name == "sensitive" {
+				findings = append(findings, finding{
+					message: fmt.Sprintf("output %q exposes a sensitive value without sensitive = true", name),
+					rng:     call.Range(),
+				})
+			}
+			return nil
+		})
+	})
+	return findings
+}
+
+// isTrue reports whether attr is set to the constant true.
+func isTrue(attr *hclsyntax.Attribute) bool {
+	if attr == nil {
+		return false
+	}
+	value, diags := attr.Expr.Value(nil)
+	return !diags.HasErrors() && value.RawEquals(cty.True)
+}
+
+// checkSnakeCase finds names that are not snake_case.
+func checkSnakeCase(module *hclsort.Module, _ Loader) []finding {
+	var findings []finding
+	report := func(kind, name string, rng hcl.Range) {
+		if !isSnakeCase(name) {
+			findings = append(findings, finding{
+				message: fmt.Sprintf("%s name %q is not snake_case", kind, name),
+				rng:     rng,
+			})
+		}
+	}
+
+	for _, file := range module.Files {
+		for _, block := range file.Body.Blocks {
+			switch {
+			case block.Type == "locals":
+				for _, attr := range block.Body.Attributes {
+					report("local value", attr.Name, attr.NameRange)
+				}
+			case (block.Type == "variable" || block.Type == "output" || block.Type == "module") && len(block.Labels) > 0:
+				report(block.Type, block.Labels[0], block.LabelRanges[0])
+			case (block.Type == "resource" || block.Type == "data") && len(block.Labels) > 1:
+				report(block.Type, block.Labels[1], block.LabelRanges[1])
+			}
+		}
+	}
+	return findings
+}
+
+// isSnakeCase reports whether name consists of lowercase words of letters and digits
+// joined by single underscores, starting with a letter.
+func isSnakeCase(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' || name[len(name)-1] == '_' {
+		return false
+	}
+	for i := range len(name) {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '_' && name[i-1] != '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// offsetToPosition converts a byte offset of text into an LSP position, whose
+// character is counted in UTF-16 code units.
+func offsetToPosition(text []byte, offset int) position {
+	offset = min(max(offset, 0), len(text))
+
+	pos := position{}
+	lineStart := 0
+	for i := range offset {
+		if text[i] == '\n' {
+			pos.Line++
+			lineStart = i + 1
+		}
+	}
+
+	for _, r := range string(text[lineStart:offset]) {
+		pos.Character += utf16.RuneLen(r)
+	}
+	return pos
+}
+
+// positionToOffset converts an LSP position into a byte offset of text. Positions
+// past the end of a line or of the text are clamped.
+func positionToOffset(text []byte, pos position) int {
+	offset := 0
+	for range pos.Line {
+		next := bytes.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+
+	for units := 0; units < pos.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRune(text[offset:])
+		units += utf16.RuneLen(r)
+		offset += size
+	}
+	return offset
+}
+
+// fullRange returns the range covering all of text.
+func fullRange(text []byte) textRange {
+	return textRange{End: offsetToPosition(text, len(text))}
+}
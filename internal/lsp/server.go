@@ -0,0 +1,383 @@
+// Package lsp implements a Language Server Protocol server that sorts Terraform and HCL
+// documents held in an editor. It speaks JSON-RPC over a pair of streams, usually the
+// process's standard input and output.
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Server is a Language Server Protocol server. It keeps the open documents in memory
+// and never touches the file system.
+type Server struct {
+	ingestor    *hclsort.Ingestor
+	in          *bufio.Reader
+	out         io.Writer
+	docs        map[string][]byte
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a Server that reads messages from in and writes to out.
+func NewServer(ingestor *hclsort.Ingestor, in io.Reader, out io.Writer) *Server {
+	return &Server{
+		ingestor: ingestor,
+		in:       bufio.NewReader(in),
+		out:      out,
+		docs:     make(map[string][]byte),
+	}
+}
+
+// Run serves messages until the client sends exit or closes the input stream.
+func (s *Server) Run() error {
+	for {
+		body, err := s.readMessage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err = json.Unmarshal(body, &msg); err != nil {
+			if err = s.reply(json.RawMessage("null"), nil, &responseError{
+				Code:    codeParseError,
+				Message: err.Error(),
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit notification received before shutdown")
+			}
+			return nil
+		}
+
+		if err = s.dispatch(&msg); err != nil {
+			return err
+		}
+	}
+}
+
+// readMessage reads the body of the next message, framed by a Content-Length header.
+func (s *Server) readMessage() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("error reading message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header '%s'", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err = io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("error reading message body: %w", err)
+	}
+	return body, nil
+}
+
+// write sends v as a single framed message.
+func (s *Server) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding message: %w", err)
+	}
+	if _, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("error writing message: %w", err)
+	}
+	return nil
+}
+
+// reply answers the request with the given ID.
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("error encoding result: %w", err)
+		}
+		resp.Result = raw
+	}
+	return s.write(resp)
+}
+
+// dispatch handles a request or a notification. Only I/O errors are returned.
+func (s *Server) dispatch(msg *message) error {
+	if len(msg.ID) == 0 {
+		return s.handleNotification(msg)
+	}
+
+	result, rpcErr := s.handleRequest(msg)
+	return s.reply(msg.ID, result, rpcErr)
+}
+
+// handleRequest computes the result of a request.
+func (s *Server) handleRequest(msg *message) (any, *responseError) {
+	switch {
+	case s.shutdown:
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	case msg.Method == "initialize":
+		s.initialized = true
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:                syncKindFull,
+				DocumentFormattingProvider:      true,
+				DocumentRangeFormattingProvider: true,
+				CodeActionProvider:              codeActionOptions{CodeActionKinds: []string{codeActionQuick}},
+			},
+			ServerInfo: serverInfo{Name: "tfsort"},
+		}, nil
+	case !s.initialized:
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/formatting":
+		return s.formatting(msg.Params)
+	case "textDocument/rangeFormatting":
+		return s.rangeFormatting(msg.Params)
+	case "textDocument/codeAction":
+		return s.codeAction(msg.Params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// handleNotification updates the open documents. Malformed notifications are ignored,
+// since there is no way to report them to the client.
+func (s *Server) handleNotification(msg *message) error {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		s.docs[params.TextDocument.URI] = []byte(params.TextDocument.Text)
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// The server only advertises full document sync, so the last change holds the whole text.
+		s.docs[params.TextDocument.URI] = []byte(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		return s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return s.publishDiagnostics(params.TextDocument.URI)
+	default:
+		return nil
+	}
+}
+
+// document returns the text of an open document.
+func (s *Server) document(uri string) ([]byte, *responseError) {
+	text, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "document is not open: " + uri}
+	}
+	return text, nil
+}
+
+// formatting sorts the whole document. Documents that fail to parse get no edits;
+// the parse errors are already published as diagnostics.
+func (s *Server) formatting(raw json.RawMessage) (any, *responseError) {
+	var params formattingParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	text, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	edit, ok := s.sortDocument(params.TextDocument.URI, text)
+	if !ok {
+		return []textEdit{}, nil
+	}
+	return []textEdit{edit}, nil
+}
+
+// rangeFormatting sorts only the top-level blocks that overlap the selection.
+func (s *Server) rangeFormatting(raw json.RawMessage) (any, *responseError) {
+	var params rangeFormattingParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	text, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	edit, err := s.ingestor.SortRange(
+		text,
+		uriToFilename(params.TextDocument.URI),
+		positionToOffset(text, params.Range.Start),
+		positionToOffset(text, params.Range.End),
+	)
+	if err != nil || edit == nil || bytes.Equal(edit.Text, text[edit.Start:edit.End]) {
+		return []textEdit{}, nil
+	}
+
+	return []textEdit{{
+		Range: textRange{
+			Start: offsetToPosition(text, edit.Start),
+			End:   offsetToPosition(text, edit.End),
+		},
+		NewText: string(edit.Text),
+	}}, nil
+}
+
+// codeAction offers a quick fix that sorts the document for unsorted diagnostics.
+func (s *Server) codeAction(raw json.RawMessage) (any, *responseError) {
+	var params codeActionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	text, rpcErr := s.document(params.TextDocument.URI)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var fixes []diagnostic
+	for _, d := range params.Context.Diagnostics {
+		if d.Source == diagnosticSource && d.Code == diagnosticCode {
+			fixes = append(fixes, d)
+		}
+	}
+	if len(fixes) == 0 {
+		return []codeAction{}, nil
+	}
+
+	edit, ok := s.sortDocument(params.TextDocument.URI, text)
+	if !ok {
+		return []codeAction{}, nil
+	}
+
+	return []codeAction{{
+		Title:       "Sort blocks and attributes",
+		Kind:        codeActionQuick,
+		Diagnostics: fixes,
+		IsPreferred: true,
+		Edit: workspaceEdit{
+			Changes: map[string][]textEdit{params.TextDocument.URI: {edit}},
+		},
+	}}, nil
+}
+
+// sortDocument runs the sorting pipeline on text. It returns an edit replacing the whole
+// document and whether the text changed.
+func (s *Server) sortDocument(uri string, text []byte) (textEdit, bool) {
+	sorted, err := s.ingestor.Sort(text, uriToFilename(uri))
+	if err != nil || bytes.Equal(sorted, text) {
+		return textEdit{}, false
+	}
+	return textEdit{Range: fullRange(text), NewText: string(sorted)}, true
+}
+
+// publishDiagnostics sends the diagnostics of a document. Closed documents get an empty list.
+func (s *Server) publishDiagnostics(uri string) error {
+	diags := []diagnostic{}
+	if text, ok := s.docs[uri]; ok {
+		diags = s.diagnostics(uri, text)
+	}
+
+	return s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+}
+
+// diagnostics reports the unsorted items of a document, or its parse errors.
+func (s *Server) diagnostics(uri string, text []byte) []diagnostic {
+	items, err := s.ingestor.FindUnsorted(text, uriToFilename(uri))
+	if err != nil {
+		return errorDiagnostics(text, err)
+	}
+
+	diags := make([]diagnostic, 0, len(items))
+	for _, item := range items {
+		diags = append(diags, diagnostic{
+			Range:    byteRange(text, item.Range),
+			Severity: severityWarning,
+			Code:     diagnosticCode,
+			Source:   diagnosticSource,
+			Message:  item.Description + " is not sorted",
+		})
+	}
+	return diags
+}
+
+// errorDiagnostics converts a parse error into diagnostics.
+func errorDiagnostics(text []byte, err error) []diagnostic {
+	var hclDiags hcl.Diagnostics
+	if !errors.As(err, &hclDiags) {
+		return []diagnostic{{Severity: severityError, Source: diagnosticSource, Message: err.Error()}}
+	}
+
+	diags := make([]diagnostic, 0, len(hclDiags))
+	for _, d := range hclDiags {
+		diag := diagnostic{
+			Severity: severityWarning,
+			Source:   diagnosticSource,
+			Message:  d.Summary,
+		}
+		if d.Detail != "" {
+			diag.Message += ": " + d.Detail
+		}
+		if d.Severity == hcl.DiagError {
+			diag.Severity = severityError
+		}
+		if d.Subject != nil {
+			diag.Range = byteRange(text, *d.Subject)
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// byteRange converts an HCL range into an LSP range using its byte offsets.
+func byteRange(text []byte, rng hcl.Range) textRange {
+	return textRange{
+		Start: offsetToPosition(text, rng.Start.Byte),
+		End:   offsetToPosition(text, rng.End.Byte),
+	}
+}
+
+// uriToFilename returns the file path of a file URI, or the URI itself for other schemes.
+func uriToFilename(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
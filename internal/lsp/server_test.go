@@ -0,0 +1,355 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/lsp"
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	docURI   = "file:///work/variables.tf"
+	unsorted = "variable \"b\" {}\nvariable \"a\" {}\n"
+	sorted   = "variable \"a\" {}\n\nvariable \"b\" {}\n"
+)
+
+// received is a message written by the server.
+type received struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+type edit struct {
+	Range struct {
+		Start struct {
+			Line      int `json:"line"`
+			Character int `json:"character"`
+		} `json:"start"`
+		End struct {
+			Line      int `json:"line"`
+			Character int `json:"character"`
+		} `json:"end"`
+	} `json:"range"`
+	NewText string `json:"newText"`
+}
+
+type diagnostic struct {
+	Range struct {
+		Start struct {
+			Line int `json:"line"`
+		} `json:"start"`
+	} `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// session frames the given messages, runs a server over them and returns its replies.
+func session(t *testing.T, messages ...string) ([]received, error) {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, msg := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	var out bytes.Buffer
+	runErr := lsp.NewServer(hclsort.NewIngestor(), &in, &out).Run()
+
+	var replies []received
+	reader := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read reply header: %v", err)
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			t.Fatalf("Invalid Content-Length: %v", err)
+		}
+		body := make([]byte, length)
+		if _, err = io.ReadFull(reader, body); err != nil {
+			t.Fatalf("Failed to read reply body: %v", err)
+		}
+
+		var reply received
+		if err = json.Unmarshal(body, &reply); err != nil {
+			t.Fatalf("Failed to decode reply %s: %v", body, err)
+		}
+		replies = append(replies, reply)
+	}
+
+	return replies, runErr
+}
+
+func request(id int, method string, params any) string {
+	return encode(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+}
+
+func notify(method string, params any) string {
+	return encode(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func encode(msg map[string]any) string {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return string(raw)
+}
+
+func open(text string) string {
+	return notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": docURI, "languageId": "terraform", "version": 1, "text": text},
+	})
+}
+
+func doc() map[string]any {
+	return map[string]any{"uri": docURI}
+}
+
+// reply returns the response to the request with the given ID.
+func reply(t *testing.T, replies []received, id int) received {
+	t.Helper()
+
+	for _, r := range replies {
+		if string(r.ID) == strconv.Itoa(id) {
+			return r
+		}
+	}
+	t.Fatalf("no reply to request %d", id)
+	return received{}
+}
+
+// published returns the diagnostics of the last publishDiagnostics notification.
+func published(t *testing.T, replies []received) []diagnostic {
+	t.Helper()
+
+	var diags []diagnostic
+	for _, r := range replies {
+		if r.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params struct {
+			Diagnostics []diagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(r.Params, &params); err != nil {
+			t.Fatalf("Failed to decode diagnostics: %v", err)
+		}
+		diags = params.Diagnostics
+	}
+	return diags
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	initialize := request(1, "initialize", map[string]any{})
+	shutdown := request(99, "shutdown", nil)
+	exit := notify("exit", nil)
+
+	t.Run("Formatting", func(t *testing.T) {
+		t.Parallel()
+
+		replies, err := session(t,
+			initialize,
+			open(unsorted),
+			request(2, "textDocument/formatting", map[string]any{"textDocument": doc()}),
+			shutdown,
+			exit,
+		)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var edits []edit
+		if err = json.Unmarshal(reply(t, replies, 2).Result, &edits); err != nil {
+			t.Fatalf("Failed to decode edits: %v", err)
+		}
+		if len(edits) != 1 {
+			t.Fatalf("expected a single edit, got %+v", edits)
+		}
+		if diff := cmp.Diff(sorted, edits[0].NewText); diff != "" {
+			t.Errorf("unexpected edit (-want +got):\n%s", diff)
+		}
+		if edits[0].Range.End.Line != 2 || edits[0].Range.End.Character != 0 {
+			t.Errorf("expected edit to cover the whole document, got %+v", edits[0].Range)
+		}
+	})
+
+	t.Run("Formatting sorted document", func(t *testing.T) {
+		t.Parallel()
+
+		replies, err := session(t,
+			initialize,
+			open(sorted),
+			request(2, "textDocument/formatting", map[string]any{"textDocument": doc()}),
+		)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if got := string(reply(t, replies, 2).Result); got != "[]" {
+			t.Errorf("expected no edits, got %s", got)
+		}
+		if diags := published(t, replies); len(diags) != 0 {
+			t.Errorf("expected no diagnostics, got %+v", diags)
+		}
+	})
+
+	t.Run("Range formatting sorts only selected blocks", func(t *testing.T) {
+		t.Parallel()
+
+		text := "output \"d\" {}\noutput \"c\" {}\n\nvariable \"b\" {}\nvariable \"a\" {}\n"
+		replies, err := session(t,
+			initialize,
+			open(text),
+			request(2, "textDocument/rangeFormatting", map[string]any{
+				"textDocument": doc(),
+				"range": map[string]any{
+					"start": map[string]int{"line": 3, "character": 0},
+					"end":   map[string]int{"line": 4, "character": 5},
+				},
+			}),
+		)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var edits []edit
+		if err = json.Unmarshal(reply(t, replies, 2).Result, &edits); err != nil {
+			t.Fatalf("Failed to decode edits: %v", err)
+		}
+		if len(edits) != 1 {
+			t.Fatalf("expected a single edit, got %+v", edits)
+		}
+		if edits[0].Range.Start.Line != 3 || edits[0].Range.End.Line != 5 {
+			t.Errorf("expected edit to cover lines 3-5, got %+v", edits[0].Range)
+		}
+		if diff := cmp.Diff(sorted, edits[0].NewText); diff != "" {
+			t.Errorf("unexpected edit (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Diagnostics and quick fix", func(t *testing.T) {
+		t.Parallel()
+
+		replies, err := session(t, initialize, open(unsorted))
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		diags := published(t, replies)
+		if len(diags) != 2 {
+			t.Fatalf("expected two diagnostics, got %+v", diags)
+		}
+		for _, d := range diags {
+			if d.Source != "tfsort" || d.Code != "unsorted" || d.Severity != 2 {
+				t.Errorf("unexpected diagnostic %+v", d)
+			}
+		}
+		if !strings.Contains(diags[0].Message, `variable "a"`) || diags[0].Range.Start.Line != 1 {
+			t.Errorf("expected first diagnostic on variable \"a\", got %+v", diags[0])
+		}
+
+		replies, err = session(t,
+			initialize,
+			open(unsorted),
+			request(2, "textDocument/codeAction", map[string]any{
+				"textDocument": doc(),
+				"range":        diags[0].Range,
+				"context":      map[string]any{"diagnostics": diags[:1]},
+			}),
+		)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var actions []struct {
+			Kind string `json:"kind"`
+			Edit struct {
+				Changes map[string][]edit `json:"changes"`
+			} `json:"edit"`
+		}
+		if err = json.Unmarshal(reply(t, replies, 2).Result, &actions); err != nil {
+			t.Fatalf("Failed to decode code actions: %v", err)
+		}
+		if len(actions) != 1 || actions[0].Kind != "quickfix" {
+			t.Fatalf("expected a single quick fix, got %+v", actions)
+		}
+		if edits := actions[0].Edit.Changes[docURI]; len(edits) != 1 || edits[0].NewText != sorted {
+			t.Errorf("expected quick fix to sort the document, got %+v", edits)
+		}
+	})
+
+	t.Run("Parse errors and document lifecycle", func(t *testing.T) {
+		t.Parallel()
+
+		replies, err := session(t,
+			initialize,
+			open(`variable "a" {`),
+			notify("textDocument/didChange", map[string]any{
+				"textDocument":   map[string]any{"uri": docURI, "version": 2},
+				"contentChanges": []map[string]string{{"text": unsorted}},
+			}),
+			notify("textDocument/didClose", map[string]any{"textDocument": doc()}),
+			request(2, "textDocument/formatting", map[string]any{"textDocument": doc()}),
+		)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		var counts []int
+		for _, r := range replies {
+			if r.Method == "textDocument/publishDiagnostics" {
+				counts = append(counts, len(published(t, []received{r})))
+			}
+		}
+		if len(counts) != 3 || counts[0] == 0 || counts[1] != 2 || counts[2] != 0 {
+			t.Errorf("unexpected diagnostic counts on open, change and close: %v", counts)
+		}
+		if first := published(t, replies[1:2]); len(first) == 0 || first[0].Severity != 1 {
+			t.Errorf("expected an error diagnostic for the parse error, got %+v", first)
+		}
+		if r := reply(t, replies, 2); r.Error == nil {
+			t.Error("expected an error for formatting a closed document")
+		}
+	})
+
+	t.Run("Protocol errors", func(t *testing.T) {
+		t.Parallel()
+
+		replies, err := session(t,
+			request(1, "textDocument/formatting", map[string]any{"textDocument": doc()}),
+			request(2, "initialize", map[string]any{}),
+			request(3, "workspace/symbol", map[string]any{}),
+			exit,
+		)
+		if err == nil {
+			t.Error("expected an error for exit without shutdown")
+		}
+		if r := reply(t, replies, 1); r.Error == nil || r.Error.Code != -32002 {
+			t.Errorf("expected server not initialized error, got %+v", r.Error)
+		}
+		if r := reply(t, replies, 3); r.Error == nil || r.Error.Code != -32601 {
+			t.Errorf("expected method not found error, got %+v", r.Error)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+// Package tfvars keeps variable definition files in line with a module's variables.
+package tfvars
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// placeholderSuffix ends the comment lines that Sync adds for missing variables.
+const placeholderSuffix = " = null # required"
+
+// Options controls how Sync changes a file.
+type Options struct {
+	// RemoveStale drops the keys that no variable declares.
+	RemoveStale bool
+	// AddMissing adds a commented placeholder for every required variable without a key.
+	AddMissing bool
+	// NoFormat leaves the keys unaligned.
+	NoFormat bool
+}
+
+// Key is a key of a variable definitions file.
+type Key struct {
+	Name  string
+	Range hcl.Range
+}
+
+// Result is the outcome of Sync.
+type Result struct {
+	// Stale are the keys that no variable declares.
+	Stale []Key
+	// Missing are the required variables that have no key.
+	Missing []string
+	// Content is the synced file, with LF line endings.
+	Content []byte
+}
+
+// Sync compares the keys of the variable definitions in src, which must use LF line
+// endings, with the variables of module. The returned content has its keys in the order
+// the variables are declared, followed by the stale keys unless they are removed. Keys
+// keep the comments above them.
+func Sync(module *hclsort.Module, src []byte, filename string, opts Options) (*Result, error) {
+	file, diags := hclsyntax.ParseConfig(src, filename, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("error parsing HCL content from '%s': %w", filename, diags)
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok || len(body.Blocks) > 0 {
+		return nil, fmt.Errorf("'%s' must only contain variable values", filename)
+	}
+
+	attrs := make([]*hclsyntax.Attribute, 0, len(body.Attributes))
+	for _, attr := range body.Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(a, b int) bool { return attrs[a].SrcRange.Start.Byte < attrs[b].SrcRange.Start.Byte })
+
+	// Placeholders are added again for the variables that are still missing.
+	placeholders := placeholderPattern()
+	items := map[string][]byte{}
+	header, trailer := src, []byte(nil)
+	floor := 0
+	for idx, attr := range attrs {
+		start, end := itemBounds(src, attr.SrcRange, floor)
+		if idx == 0 {
+			header = src[:start]
+		} else {
+			// Comments between two keys stay with the key below them.
+			start = floor
+		}
+		item := placeholders.ReplaceAll(src[start:end], nil)
+		items[attr.Name] = bytes.TrimRight(bytes.TrimLeft(item, "\n"), " \t\n")
+		floor = end
+		trailer = src[end:]
+	}
+
+	result := &Result{}
+	var out bytes.Buffer
+	out.Write(headerText(placeholders.ReplaceAll(header, nil)))
+	declared := map[string]bool{}
+	for _, v := range module.Variables() {
+		declared[v.Name] = true
+		switch item, present := items[v.Name]; {
+		case present:
+			out.Write(item)
+			out.WriteByte('\n')
+		case v.Required:
+			result.Missing = append(result.Missing, v.Name)
+			if opts.AddMissing {
+				fmt.Fprintf(&out, "# %s%s: %s\n", v.Name, placeholderSuffix, v.Type)
+			}
+		}
+	}
+	for _, attr := range attrs {
+		if declared[attr.Name] {
+			continue
+		}
+		result.Stale = append(result.Stale, Key{Name: attr.Name, Range: attr.NameRange})
+		if !opts.RemoveStale {
+			out.Write(items[attr.Name])
+			out.WriteByte('\n')
+		}
+	}
+	if rest := bytes.TrimLeft(placeholders.ReplaceAll(trailer, nil), "\n"); len(rest) > 0 {
+		out.WriteByte('\n')
+		out.Write(rest)
+	}
+
+	result.Content = out.Bytes()
+	if trimmed := bytes.TrimRight(result.Content, " \t\n"); len(trimmed) > 0 {
+		result.Content = append(trimmed, '\n')
+	}
+	if !opts.NoFormat {
+		result.Content = hclwrite.Format(result.Content)
+	}
+	return result, nil
+}
+
+// itemBounds returns the bounds of the whole lines of rng, together with the comment
+// lines directly above it, without crossing floor.
+func itemBounds(src []byte, rng hcl.Range, floor int) (int, int) {
+	start := bytes.LastIndexByte(src[:rng.Start.Byte], '\n') + 1
+	for start > floor {
+		prevStart := bytes.LastIndexByte(src[:start-1], '\n') + 1
+		line := bytes.TrimSpace(src[prevStart : start-1])
+		if prevStart < floor || !(bytes.HasPrefix(line, []byte("#")) || bytes.HasPrefix(line, []byte("//"))) {
+			break
+		}
+		start = prevStart
+	}
+
+	end := rng.End.Byte
+	if lineEnd := bytes.IndexByte(src[end:], '\n'); lineEnd >= 0 {
+		end += lineEnd + 1
+	} else {
+		end = len(src)
+	}
+	return start, end
+}
+
+// placeholderPattern matches the placeholder lines added for missing variables.
+func placeholderPattern() *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^# [A-Za-z0-9_-]+` + regexp.QuoteMeta(placeholderSuffix) + `.*\n?`)
+}
+
+// headerText returns the text above the first key followed by a blank line, or nothing
+// when it is blank.
+func headerText(text []byte) []byte {
+	text = bytes.TrimRight(text, " \t\n")
+	if len(text) == 0 {
+		return nil
+	}
+	return append(text, '\n', '\n')
+}
@@ -0,0 +1,92 @@
+package tfvars_test
+
+import (
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/AlexNabokikh/tfsort/internal/tfvars"
+	"github.com/google/go-cmp/cmp"
+)
+
+const variablesTF = `variable "region" {
+  type = string
+}
+
+variable "name" {
+  type    = string
+  default = "x"
+}
+
+variable "instance_count" {
+  type = number
+}
+`
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	file, err := hclsort.NewModuleFile("variables.tf", []byte(variablesTF))
+	if err != nil {
+		t.Fatalf("NewModuleFile failed: %v", err)
+	}
+	module := &hclsort.Module{Files: []*hclsort.ModuleFile{file}}
+
+	src := "# Production\n\nname = \"prod\"\nold = true\n\n# where\nregion = \"eu-west-1\"\n"
+
+	tests := []struct {
+		name        string
+		src         string
+		opts        tfvars.Options
+		want        string
+		wantStale   []string
+		wantMissing []string
+	}{
+		{
+			name:        "Reorders and keeps stale keys",
+			src:         src,
+			want:        "# Production\n\n# where\nregion = \"eu-west-1\"\nname   = \"prod\"\nold    = true\n",
+			wantStale:   []string{"old"},
+			wantMissing: []string{"instance_count"},
+		},
+		{
+			name: "Removes stale keys and adds placeholders",
+			src:  src,
+			opts: tfvars.Options{RemoveStale: true, AddMissing: true},
+			want: "# Production\n\n# where\nregion = \"eu-west-1\"\nname   = \"prod\"\n" +
+				"# instance_count = null # required: number\n",
+			wantStale:   []string{"old"},
+			wantMissing: []string{"instance_count"},
+		},
+		{
+			name: "Replaces placeholders of set variables",
+			src:  "region = \"a\"\n# instance_count = null # required: number\ninstance_count = 2\n",
+			opts: tfvars.Options{AddMissing: true},
+			want: "region         = \"a\"\ninstance_count = 2\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, syncErr := tfvars.Sync(module, []byte(tc.src), "prod.tfvars", tc.opts)
+			if syncErr != nil {
+				t.Fatalf("Sync failed: %v", syncErr)
+			}
+			if diff := cmp.Diff(tc.want, string(result.Content)); diff != "" {
+				t.Errorf("unexpected content (-want +got):\n%s", diff)
+			}
+
+			var stale []string
+			for _, key := range result.Stale {
+				stale = append(stale, key.Name)
+			}
+			if diff := cmp.Diff(tc.wantStale, stale); diff != "" {
+				t.Errorf("unexpected stale keys (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantMissing, result.Missing); diff != "" {
+				t.Errorf("unexpected missing variables (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
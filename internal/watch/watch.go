@@ -0,0 +1,290 @@
+// Package watch reports files that change under a set of directory trees. It relies on
+// the operating system's file notifications and falls back to polling where they are
+// not available.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	defaultDebounce = 200 * time.Millisecond
+	defaultInterval = time.Second
+)
+
+// Filter reports whether path, found while watching root, should be ignored. Ignored
+// directories are not descended into.
+type Filter func(root, path string, isDir bool) bool
+
+// Watcher watches directory trees for created or modified files.
+type Watcher struct {
+	// Roots are the directories to watch recursively.
+	Roots []string
+	// Skip, if set, excludes files and directories from watching.
+	Skip Filter
+	// Debounce is how long the Watcher waits for a burst of events to settle.
+	Debounce time.Duration
+	// Interval is the time between two scans when polling.
+	Interval time.Duration
+	// Poll forces polling even when file notifications are available.
+	Poll bool
+	// Logger receives warnings. Nil means the process's stderr.
+	Logger *log.Logger
+}
+
+// New returns a Watcher for roots with default timings.
+func New(roots []string, skip Filter) *Watcher {
+	return &Watcher{
+		Roots:    roots,
+		Skip:     skip,
+		Debounce: defaultDebounce,
+		Interval: defaultInterval,
+	}
+}
+
+// Run calls handle for every file that is created or modified, until ctx is done.
+// Changes are collected until no event arrives for Debounce and then handled in
+// path order, once per file. handle is never called concurrently.
+func (w *Watcher) Run(ctx context.Context, handle func(path string)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan string)
+	errc := make(chan error, 1)
+
+	run, err := w.start(ctx, changes)
+	if err != nil {
+		return err
+	}
+	go func() { errc <- run() }()
+
+	pending := map[string]bool{}
+	timer := time.NewTimer(w.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err = <-errc:
+			return err
+		case path := <-changes:
+			pending[path] = true
+			timer.Reset(w.Debounce)
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			clear(pending)
+			for _, path := range paths {
+				handle(path)
+			}
+		}
+	}
+}
+
+// start prepares the source of changes: file notifications when available, polling
+// otherwise. The returned function sends changes until ctx is done.
+func (w *Watcher) start(ctx context.Context, changes chan<- string) (func() error, error) {
+	if !w.Poll {
+		notifier, err := w.newNotifier()
+		if err == nil {
+			return func() error { return w.notify(ctx, notifier, changes) }, nil
+		}
+		w.logger().Printf("Warning: file notifications unavailable (%v), polling every %s", err, w.Interval)
+	}
+
+	snapshot, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return w.poll(ctx, snapshot, changes) }, nil
+}
+
+// newNotifier returns a file notification watcher registered on every directory under
+// the roots, or an error when notifications cannot be used.
+func (w *Watcher) newNotifier() (*fsnotify.Watcher, error) {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range w.Roots {
+		if err = w.addTree(notifier, root, root, nil); err != nil {
+			_ = notifier.Close()
+			return nil, err
+		}
+	}
+	return notifier, nil
+}
+
+// addTree registers dir and its subdirectories with notifier. Files found along the
+// way are sent to found, if it is not nil.
+func (w *Watcher) addTree(notifier *fsnotify.Watcher, root, dir string, found func(string)) error {
+	return w.walk(root, dir, func(path string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			if found != nil {
+				found(path)
+			}
+			return nil
+		}
+		if err := notifier.Add(path); err != nil {
+			return fmt.Errorf("failed to watch directory '%s': %w", path, err)
+		}
+		return nil
+	})
+}
+
+// walk calls visit for every directory and file under dir that Skip does not exclude.
+// Entries that vanish or cannot be read during the walk are ignored.
+func (w *Watcher) walk(root, dir string, visit func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrPermission) || errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if w.skip(root, path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return visit(path, d)
+	})
+}
+
+// notify forwards file notifications to changes until ctx is done.
+func (w *Watcher) notify(ctx context.Context, notifier *fsnotify.Watcher, changes chan<- string) error {
+	defer notifier.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-notifier.Errors:
+			return fmt.Errorf("error watching files: %w", err)
+		case event := <-notifier.Events:
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			root := w.rootOf(event.Name)
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				// Files may be created in a new directory before it is watched.
+				send := func(path string) { sendChange(ctx, changes, path) }
+				if err = w.addTree(notifier, root, event.Name, send); err != nil {
+					w.logger().Printf("Warning: %v", err)
+				}
+				continue
+			}
+			if !w.skip(root, event.Name, false) {
+				sendChange(ctx, changes, event.Name)
+			}
+		}
+	}
+}
+
+// poll scans the roots every Interval and sends files whose size or modification time
+// differ from the previous scan.
+func (w *Watcher) poll(ctx context.Context, snapshot map[string]fileState, changes chan<- string) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := w.scan()
+			if err != nil {
+				return err
+			}
+			for path, state := range current {
+				if previous, ok := snapshot[path]; !ok || previous != state {
+					sendChange(ctx, changes, path)
+				}
+			}
+			snapshot = current
+		}
+	}
+}
+
+// fileState is what polling compares to detect a change.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// scan records the state of every file under the roots.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	states := map[string]fileState{}
+	for _, root := range w.Roots {
+		err := w.walk(root, root, func(path string, d fs.DirEntry) error {
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil //nolint:nilerr // the file was removed during the scan
+			}
+			states[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error scanning directory '%s': %w", root, err)
+		}
+	}
+	return states, nil
+}
+
+// rootOf returns the watched root that contains path.
+func (w *Watcher) rootOf(path string) string {
+	best := ""
+	for _, root := range w.Roots {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > len(best) {
+			best = root
+		}
+	}
+	return best
+}
+
+// skip applies the Filter, if any.
+func (w *Watcher) skip(root, path string, isDir bool) bool {
+	return w.Skip != nil && w.Skip(root, path, isDir)
+}
+
+// logger returns the configured logger or one writing to the process's stderr.
+func (w *Watcher) logger() *log.Logger {
+	if w.Logger != nil {
+		return w.Logger
+	}
+	return log.New(os.Stderr, "", 0)
+}
+
+// sendChange delivers path unless ctx is done first.
+func sendChange(ctx context.Context, changes chan<- string, path string) {
+	select {
+	case changes <- path:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,85 @@
+package watch_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlexNabokikh/tfsort/internal/watch"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	for name, poll := range map[string]bool{"Notifications": false, "Polling": true} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			if err := os.Mkdir(filepath.Join(root, "skipped"), 0755); err != nil {
+				t.Fatalf("Failed to create directory: %v", err)
+			}
+
+			w := watch.New([]string{root}, func(_, path string, isDir bool) bool {
+				return isDir && filepath.Base(path) == "skipped" || filepath.Ext(path) == ".txt"
+			})
+			w.Poll = poll
+			w.Debounce = 50 * time.Millisecond
+			w.Interval = 20 * time.Millisecond
+			w.Logger = log.New(io.Discard, "", 0)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			handled := make(chan string, 10)
+			done := make(chan error, 1)
+			go func() {
+				done <- w.Run(ctx, func(path string) { handled <- path })
+			}()
+			// Give the watcher time to take its initial snapshot.
+			time.Sleep(100 * time.Millisecond)
+
+			want := filepath.Join(root, "nested", "main.tf")
+			writeFile(t, filepath.Join(root, "skipped", "main.tf"))
+			writeFile(t, filepath.Join(root, "notes.txt"))
+			writeFile(t, want)
+			// A burst of writes to the same file is handled once.
+			writeFile(t, want)
+
+			select {
+			case got := <-handled:
+				if got != want {
+					t.Errorf("expected change of %s, got %s", want, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for a change")
+			}
+
+			select {
+			case got := <-handled:
+				t.Errorf("unexpected extra change of %s", got)
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Run failed: %v", err)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("variable \"a\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
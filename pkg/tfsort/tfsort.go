@@ -0,0 +1,210 @@
+// Package tfsort sorts Terraform, OpenTofu and HCL sources.
+//
+// It exposes the same pipeline as the tfsort command line tool as a library. The functions
+// in this package never read from or write to the process's standard streams or the file
+// system; diagnostics are returned as values.
+package tfsort
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AlexNabokikh/tfsort/internal/hclsort"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// LineEndings selects the line terminator of the sorted output.
+type LineEndings string
+
+const (
+	// LineEndingsAuto keeps the line terminator detected in the input.
+	LineEndingsAuto LineEndings = "auto"
+	// LineEndingsLF always writes "\n".
+	LineEndingsLF LineEndings = "lf"
+	// LineEndingsCRLF always writes "\r\n".
+	LineEndingsCRLF LineEndings = "crlf"
+)
+
+// Severity is the importance of a Diagnostic.
+type Severity int
+
+const (
+	// SeverityError marks a problem that prevented sorting.
+	SeverityError Severity = iota + 1
+	// SeverityWarning marks a problem that did not prevent sorting.
+	SeverityWarning
+)
+
+// Options configures Sort. The zero value sorts variable and output blocks,
+// reformats the file and keeps its line endings.
+type Options struct {
+	// SortableBlocks lists the top-level block types sorted by their first label.
+	// When empty, variable and output blocks are sorted.
+	SortableBlocks []string
+	// NoFormat only reorders blocks and attributes and leaves all other bytes untouched.
+	NoFormat bool
+	// LineEndings overrides the line terminator of the output. Empty means LineEndingsAuto.
+	LineEndings LineEndings
+}
+
+// Pos is a position in a source file. Line and Column are 1-based, Byte is 0-based.
+type Pos struct {
+	Line   int
+	Column int
+	Byte   int
+}
+
+// Range is a span of a source file.
+type Range struct {
+	Filename string
+	Start    Pos
+	End      Pos
+}
+
+// Diagnostic describes a problem found while sorting.
+type Diagnostic struct {
+	Severity Severity
+	Summary  string
+	Detail   string
+	// Subject is the part of the source the diagnostic refers to, if known.
+	Subject *Range
+}
+
+// Result describes the outcome of a Sort call.
+type Result struct {
+	// Changed reports whether the output differs from the input.
+	Changed     bool
+	Diagnostics []Diagnostic
+}
+
+// Error is returned when the source could not be sorted. It carries the diagnostics
+// that caused the failure.
+type Error struct {
+	Filename    string
+	Diagnostics []Diagnostic
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return "failed to sort " + e.Filename
+	}
+	return fmt.Sprintf("failed to sort %s: %s", e.Filename, e.Diagnostics[0])
+}
+
+// String formats the diagnostic as "file:line,column: summary; detail".
+func (d Diagnostic) String() string {
+	msg := d.Summary
+	if d.Detail != "" {
+		msg += "; " + d.Detail
+	}
+	if d.Subject == nil {
+		return msg
+	}
+	return fmt.Sprintf("%s:%d,%d: %s", d.Subject.Filename, d.Subject.Start.Line, d.Subject.Start.Column, msg)
+}
+
+// Sort sorts src and returns the sorted content. The filename is only used in
+// diagnostics and to recognize the file type.
+func Sort(src []byte, filename string, opts Options) ([]byte, Result, error) {
+	result := Result{}
+
+	ingestor, err := newIngestor(opts)
+	if err != nil {
+		return nil, result, err
+	}
+
+	if extErr := hclsort.CheckFileExtension(filename, ingestor.AllowedTypes); extErr != nil {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{
+			Severity: SeverityWarning,
+			Summary:  extErr.Error(),
+		})
+	}
+
+	out, err := ingestor.Sort(src, filename)
+	if err != nil {
+		sortErr := &Error{Filename: filename, Diagnostics: toDiagnostics(err)}
+		result.Diagnostics = append(result.Diagnostics, sortErr.Diagnostics...)
+		return nil, result, sortErr
+	}
+
+	result.Changed = !bytes.Equal(src, out)
+	return out, result, nil
+}
+
+// SortReader reads a source from r, sorts it and writes the result to w.
+func SortReader(r io.Reader, w io.Writer, filename string, opts Options) (Result, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, fmt.Errorf("error reading %s: %w", filename, err)
+	}
+
+	out, result, err := Sort(src, filename, opts)
+	if err != nil {
+		return result, err
+	}
+
+	if _, err = w.Write(out); err != nil {
+		return result, fmt.Errorf("error writing %s: %w", filename, err)
+	}
+	return result, nil
+}
+
+// newIngestor translates Options into the internal pipeline configuration.
+func newIngestor(opts Options) (*hclsort.Ingestor, error) {
+	ingestor := hclsort.NewIngestor()
+	ingestor.NoFormat = opts.NoFormat
+
+	if opts.LineEndings != "" {
+		lineEndings, err := hclsort.ParseLineEndings(string(opts.LineEndings))
+		if err != nil {
+			return nil, err
+		}
+		ingestor.LineEndings = lineEndings
+	}
+
+	if len(opts.SortableBlocks) > 0 {
+		ingestor.AllowedBlocks = make(map[string]bool, len(opts.SortableBlocks))
+		for _, blockType := range opts.SortableBlocks {
+			ingestor.AllowedBlocks[blockType] = true
+		}
+	}
+
+	return ingestor, nil
+}
+
+// toDiagnostics extracts HCL diagnostics from err, falling back to a single error diagnostic.
+func toDiagnostics(err error) []Diagnostic {
+	var hclDiags hcl.Diagnostics
+	if !errors.As(err, &hclDiags) {
+		return []Diagnostic{{Severity: SeverityError, Summary: err.Error()}}
+	}
+
+	diags := make([]Diagnostic, 0, len(hclDiags))
+	for _, d := range hclDiags {
+		diag := Diagnostic{
+			Severity: SeverityWarning,
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Severity == hcl.DiagError {
+			diag.Severity = SeverityError
+		}
+		if d.Subject != nil {
+			diag.Subject = toRange(*d.Subject)
+		}
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// toRange converts an HCL range.
+func toRange(r hcl.Range) *Range {
+	return &Range{
+		Filename: r.Filename,
+		Start:    Pos{Line: r.Start.Line, Column: r.Start.Column, Byte: r.Start.Byte},
+		End:      Pos{Line: r.End.Line, Column: r.End.Column, Byte: r.End.Byte},
+	}
+}
@@ -0,0 +1,153 @@
+package tfsort_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AlexNabokikh/tfsort/pkg/tfsort"
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleSort() {
+	src := []byte(`variable "region" {}
+variable "name" {}
+`)
+
+	out, result, err := tfsort.Sort(src, "variables.tf", tfsort.Options{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(result.Changed)
+	fmt.Print(string(out))
+	// Output:
+	// true
+	// variable "name" {}
+	//
+	// variable "region" {}
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Matches the command line output", func(t *testing.T) {
+		t.Parallel()
+
+		src, err := os.ReadFile("../../internal/hclsort/testdata/valid.tf")
+		if err != nil {
+			t.Fatalf("Failed to read input: %v", err)
+		}
+		want, err := os.ReadFile("../../internal/hclsort/testdata/expected.tf")
+		if err != nil {
+			t.Fatalf("Failed to read expected output: %v", err)
+		}
+
+		got, result, err := tfsort.Sort(src, "valid.tf", tfsort.Options{})
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if !result.Changed {
+			t.Error("expected Result.Changed to be true")
+		}
+		if diff := cmp.Diff(strings.TrimSpace(string(want))+"\n", string(got)); diff != "" {
+			t.Errorf("unexpected output (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Unchanged input", func(t *testing.T) {
+		t.Parallel()
+
+		src := []byte("variable \"a\" {}\n")
+		_, result, err := tfsort.Sort(src, "a.tf", tfsort.Options{})
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if result.Changed {
+			t.Error("expected Result.Changed to be false")
+		}
+	})
+
+	t.Run("Custom options", func(t *testing.T) {
+		t.Parallel()
+
+		src := []byte("module \"b\" {}\r\nmodule \"a\" {}\r\n")
+		got, _, err := tfsort.Sort(src, "main.tf", tfsort.Options{
+			SortableBlocks: []string{"module"},
+			LineEndings:    tfsort.LineEndingsLF,
+		})
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if diff := cmp.Diff("module \"a\" {}\n\nmodule \"b\" {}\n", string(got)); diff != "" {
+			t.Errorf("unexpected output (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Unsupported extension is a warning", func(t *testing.T) {
+		t.Parallel()
+
+		_, result, err := tfsort.Sort([]byte("variable \"a\" {}\n"), "vars.txt", tfsort.Options{})
+		if err != nil {
+			t.Fatalf("Sort failed: %v", err)
+		}
+		if len(result.Diagnostics) != 1 || result.Diagnostics[0].Severity != tfsort.SeverityWarning {
+			t.Errorf("expected a single warning diagnostic, got %+v", result.Diagnostics)
+		}
+	})
+
+	t.Run("Syntax errors are typed diagnostics", func(t *testing.T) {
+		t.Parallel()
+
+		_, result, err := tfsort.Sort([]byte(`variable "a" { type = string`), "broken.tf", tfsort.Options{})
+
+		var sortErr *tfsort.Error
+		if !errors.As(err, &sortErr) {
+			t.Fatalf("expected *tfsort.Error, got %T: %v", err, err)
+		}
+		if len(sortErr.Diagnostics) == 0 || len(result.Diagnostics) == 0 {
+			t.Fatal("expected diagnostics to be returned")
+		}
+
+		diag := sortErr.Diagnostics[0]
+		if diag.Severity != tfsort.SeverityError {
+			t.Errorf("expected error severity, got %v", diag.Severity)
+		}
+		if diag.Subject == nil || diag.Subject.Filename != "broken.tf" || diag.Subject.Start.Line != 1 {
+			t.Errorf("expected subject in broken.tf line 1, got %+v", diag.Subject)
+		}
+	})
+
+	t.Run("Invalid line endings", func(t *testing.T) {
+		t.Parallel()
+
+		if _, _, err := tfsort.Sort(nil, "a.tf", tfsort.Options{LineEndings: "cr"}); err == nil {
+			t.Error("Expected error for invalid line endings but got nil")
+		}
+	})
+}
+
+func TestSortReader(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	result, err := tfsort.SortReader(
+		strings.NewReader("output \"b\" {}\noutput \"a\" {}\n"),
+		&out,
+		"outputs.tf",
+		tfsort.Options{},
+	)
+	if err != nil {
+		t.Fatalf("SortReader failed: %v", err)
+	}
+	if !result.Changed {
+		t.Error("expected Result.Changed to be true")
+	}
+	if diff := cmp.Diff("output \"a\" {}\n\noutput \"b\" {}\n", out.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}